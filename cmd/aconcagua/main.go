@@ -17,6 +17,13 @@ func main() {
 	// params := tuner.GetEvaluationParams()
 	// tuner.AdamTuner(params, &dataset, tuner.ScalingFactor, 300)
 
+	// Use to train the nnue network, starting from the pieces square tables
+	// trainer := tuner.NewNNUETrainer(engine.BootstrapNetwork(), 0.0001, 1)
+	// trainer.Train(tuner.NewNNUEDataset(dataset), 100, "./internal/tuner/nets", 10)
+
+	// Fit the win rate model for the wdl output
+	// fmt.Println(tuner.FitWinRateModel(dataset, params))
+
 	// Find fixed magic numbers
 	// engine.GenerateMagicNumbersForRooksAndBishops()
 }
@@ -50,3 +50,18 @@ func Attacks(piece int, from Bitboard, blocks Bitboard) (attacks Bitboard) {
 	}
 	return
 }
+
+// AttackedSquares returns a bitboard with all the squares attacked by the pieces of the side
+func AttackedSquares(pos *Position, side Color) (attacked Bitboard) {
+	for role := King; role <= Pawn; role++ {
+		piece := pieceColor(role, side)
+		if role == Pawn {
+			attacked |= Attacks(piece, pos.Pieces[piece], pos.Sides[All])
+			continue
+		}
+		for bb := pos.Pieces[piece]; bb > 0; {
+			attacked |= Attacks(piece, bb.NextBit(), pos.Sides[All])
+		}
+	}
+	return
+}
@@ -0,0 +1,44 @@
+package engine
+
+// BenchDepth is the default depth used by the bench
+const BenchDepth = 10
+
+// BenchPositions are the positions searched by the bench
+var BenchPositions = []string{
+	StartingFenString,
+	"r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - 4 4",
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+	"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+	"r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
+	"rnbqkb1r/pp1p1ppp/2p5/4P3/2B5/8/PPP1NnPP/RNBQK2R w KQkq - 0 6",
+	"2r3k1/pp3ppp/8/3q4/8/1B6/PP3PPP/3Q2K1 w - - 0 1",
+	"r1b1k2r/ppppnppp/2n2q2/2b5/3NP3/2P1B3/PP3PPP/RN1QKB1R w KQkq - 0 1",
+	"4rrk1/pp1n3p/3q2pQ/2p1pb2/2PP4/2P3N1/P2B2PP/4RRK1 b - - 7 19",
+	"rq3rk1/ppp2ppp/1bnpb3/3N2B1/3NP3/7P/PPPQ1PP1/2KR3R w - - 7 14",
+	"8/1p4pk/6rp/3Pp3/4Qn2/2P2qP1/1B3P1P/4R1K1 b - - 0 1",
+	"8/8/4k3/3p4/3P4/4K3/8/8 w - - 0 1",
+}
+
+// Bench searches the bench positions up to the depth passed with a single thread and returns the total
+// number of nodes searched. The node count is deterministic, so it can be used to detect changes in the search
+func Bench(depth int) (nodes uint64) {
+	s := newSearchWithTable(NewTranspositionTable(16))
+	// The endgame tables are generated in the background, so the nodes would depend on when they are ready
+	s.Evaluation.Endgames = nil
+	stdout := make(chan string)
+	go func() {
+		for range stdout {
+		}
+	}()
+	defer close(stdout)
+
+	for _, fen := range BenchPositions {
+		pos := NewPosition()
+		pos.LoadFromFenString(fen)
+		s.ClearHash()
+		s.TimeControl.Initialize(DepthStrategy, int(pos.Turn), pos.FullMoveNumber, Clock{})
+		s.IterativeDeepening(pos, depth, stdout)
+		nodes += s.nodes.Load()
+	}
+	return
+}
@@ -0,0 +1,12 @@
+package engine
+
+import "testing"
+
+func TestBenchSearchesTheSameNodesOnEachRun(t *testing.T) {
+	expected := Bench(8)
+	got := Bench(8)
+
+	if got != expected {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
@@ -0,0 +1,131 @@
+package engine
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrSearchRunning is returned when a search is started while another search is running
+var ErrSearchRunning = errors.New("search already running")
+
+// SearchController handles the lifecycle of the searches of the engine. Each search runs on its own goroutine
+// with a copy of the position, so the position of the engine can be changed while thinking. Commands that
+// modify the search state while thinking are queued and executed once the search is finished
+type SearchController struct {
+	Search *Search
+	mu     sync.Mutex
+	pos    Position      // Copy of the position being searched
+	done   chan struct{} // Closed when the current search finishes. Nil if no search was started
+	queued []func()      // Commands waiting for the search to finish
+}
+
+// NewSearchController returns a new SearchController for the search passed
+func NewSearchController(s *Search) *SearchController {
+	return &SearchController{Search: s}
+}
+
+// Start starts a new search of the position passed up to the depth passed. The time control of the search
+// must be initialized before. Returns ErrSearchRunning if the engine is still thinking
+func (sc *SearchController) Start(pos *Position, depth int, stdout chan string) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if sc.thinking() {
+		return ErrSearchRunning
+	}
+	sc.flush()
+
+	sc.pos = *pos
+	done := make(chan struct{})
+	sc.done = done
+	sc.Search.searching.Store(true)
+	go func() {
+		defer close(done)
+		sc.Search.IterativeDeepening(&sc.pos, depth, stdout)
+	}()
+
+	return nil
+}
+
+// Stop signals the current search to stop and waits until it finished
+func (sc *SearchController) Stop() {
+	sc.Search.Stop()
+	sc.Wait()
+}
+
+// PonderHit switches the current search from pondering to the time limits of the clock
+func (sc *SearchController) PonderHit() {
+	sc.Search.PonderHit()
+}
+
+// Wait waits until the current search finished and executes the commands queued while thinking
+func (sc *SearchController) Wait() {
+	sc.mu.Lock()
+	done := sc.done
+	sc.mu.Unlock()
+
+	if done != nil {
+		<-done
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.flush()
+}
+
+// Thinking returns if there is a search running
+func (sc *SearchController) Thinking() bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	return sc.thinking()
+}
+
+// Run executes the command passed if the engine is not thinking. Otherwise the command is queued until the
+// search is finished. Returns true if the command was queued
+func (sc *SearchController) Run(command func()) (queued bool) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if sc.thinking() {
+		sc.queued = append(sc.queued, command)
+		return true
+	}
+	sc.flush()
+	command()
+
+	return false
+}
+
+// Flush executes the commands queued while thinking, if the search is already finished
+func (sc *SearchController) Flush() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if !sc.thinking() {
+		sc.flush()
+	}
+}
+
+// thinking returns if there is a search running. Must be called holding the lock
+// Once the best move is sent the search is considered finished, as the GUI might start a new search
+// inmediately. In that case it waits for the search goroutine to exit
+func (sc *SearchController) thinking() bool {
+	if sc.done == nil {
+		return false
+	}
+
+	if sc.Search.searching.Load() {
+		return true
+	}
+	<-sc.done
+	return false
+}
+
+// flush executes the queued commands in order. Must be called holding the lock, with no search running
+func (sc *SearchController) flush() {
+	for _, command := range sc.queued {
+		command()
+	}
+	sc.queued = sc.queued[:0]
+}
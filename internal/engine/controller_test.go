@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestControllerRejectsStartWhileThinking(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString(StartingFenString)
+	sc := NewSearchController(NewSearch())
+	stdout := make(chan string, 10000)
+
+	sc.Search.TimeControl.Initialize(InfiniteStrategy, int(pos.Turn), pos.FullMoveNumber, Clock{})
+	sc.Start(pos, MaxSearchDepth, stdout)
+	defer sc.Stop()
+
+	expected := ErrSearchRunning
+	got := sc.Start(pos, MaxSearchDepth, stdout)
+
+	if expected != got {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
+
+func TestControllerQueuesCommandsWhileThinking(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString(StartingFenString)
+	sc := NewSearchController(NewSearch())
+	stdout := make(chan string, 10000)
+
+	sc.Search.TimeControl.Initialize(InfiniteStrategy, int(pos.Turn), pos.FullMoveNumber, Clock{})
+	sc.Start(pos, MaxSearchDepth, stdout)
+
+	executed := false
+	queued := sc.Run(func() { executed = true })
+	if !queued || executed {
+		t.Errorf("Expected command to be queued while thinking")
+	}
+
+	sc.Stop()
+
+	if !executed {
+		t.Errorf("Expected command to be executed after the search finished")
+	}
+}
+
+func TestControllerStressCommandsWhileThinking(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString(StartingFenString)
+	sc := NewSearchController(NewSearch())
+	sc.Search.SetThreads(2)
+
+	for i := range 20 {
+		stdout := make(chan string, 100000)
+		sc.Search.TimeControl.Initialize(InfiniteStrategy, int(pos.Turn), pos.FullMoveNumber, Clock{})
+		if err := sc.Start(pos, MaxSearchDepth, stdout); err != nil {
+			t.Fatalf("Expected search to start, got: %v", err)
+		}
+
+		// The searched position is a copy, so the position can change while thinking
+		pos.LoadFromFenString("r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - 4 4")
+
+		var wg sync.WaitGroup
+		for range 4 {
+			wg.Go(func() {
+				sc.Run(func() { sc.Search.TranspositionTable.Resize(1 + i%4) })
+				sc.Run(func() { sc.Search.ClearHash() })
+				sc.Run(func() { sc.Search.SetThreads(1 + i%3) })
+				sc.Run(func() { sc.Search.MultiPV = 1 + i%2 })
+			})
+		}
+		wg.Wait()
+		sc.Stop()
+		pos.LoadFromFenString(StartingFenString)
+
+		if sc.Thinking() {
+			t.Errorf("Expected search to be finished after stop")
+		}
+
+		close(stdout)
+		bestMoves := 0
+		for line := range stdout {
+			if strings.HasPrefix(line, "bestmove") {
+				bestMoves++
+			}
+		}
+		if bestMoves != 1 {
+			t.Errorf("Expected: %v, got: %v", 1, bestMoves)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package engine
+
+const (
+	CorrectionHistorySize   = 16384
+	CorrectionHistoryGrain  = 256                         // Scale of the entries, to allow small updates on each node
+	CorrectionHistoryWeight = 256                         // Total weight of the moving average of the entries
+	CorrectionHistoryMax    = CorrectionHistoryGrain * 64 // Max correction stored in an entry (64 cp)
+)
+
+// CorrectionHistoryTable holds the average difference between the search score and the static evaluation
+// of the positions, indexed by side to move and a key of the position (pawn structure, material)
+type CorrectionHistoryTable [2][CorrectionHistorySize]int
+
+// get returns the correction stored for the key and side passed, in centipawns
+func (cht *CorrectionHistoryTable) get(key uint64, side Color) int {
+	return cht[side][key%CorrectionHistorySize] / CorrectionHistoryGrain
+}
+
+// update moves the entry towards the difference passed. Deeper searches have a bigger weight on the average
+func (cht *CorrectionHistoryTable) update(key uint64, side Color, diff int, depth int) {
+	entry := &cht[side][key%CorrectionHistorySize]
+	weight := min(depth+1, 16)
+
+	*entry = (*entry*(CorrectionHistoryWeight-weight) + diff*CorrectionHistoryGrain*weight) / CorrectionHistoryWeight
+	*entry = max(-CorrectionHistoryMax, min(CorrectionHistoryMax, *entry))
+}
+
+// clear clears all entries of the CorrectionHistoryTable
+func (cht *CorrectionHistoryTable) clear() {
+	*cht = CorrectionHistoryTable{}
+}
+
+// nonPawnPieces are the pieces used to compute the non pawn material key of the position
+var nonPawnPieces = [8]int{WhiteQueen, WhiteRook, WhiteBishop, WhiteKnight, BlackQueen, BlackRook, BlackBishop, BlackKnight}
+
+// nonPawnMaterialKey returns a key for the number of knights, bishops, rooks and queens of each side
+func (pos *Position) nonPawnMaterialKey() (key uint64) {
+	for _, piece := range nonPawnPieces {
+		key ^= zobristHashKeys.getPieceSquareKey(piece, pos.Pieces[piece].count())
+	}
+	return
+}
+
+// correctEval returns the static evaluation adjusted by the average of the pawn structure and material correction histories
+func (s *Search) correctEval(pos *Position, rawEval int) int {
+	correction := (s.pawnCorrection.get(pos.PawnHash, pos.Turn) + s.materialCorrection.get(pos.nonPawnMaterialKey(), pos.Turn)) / 2
+	return max(-MateScore+MaxSearchDepth+1, min(MateScore-MaxSearchDepth-1, rawEval+correction))
+}
+
+// updateCorrectionHistory records the difference between the score of the search and the static evaluation
+// of the position. Only scores that tell something about the static evaluation are used: exact scores, lower
+// bounds above the eval and upper bounds below it. Mate scores and captures as best move are discarded
+func (s *Search) updateCorrectionHistory(pos *Position, depth int, flag uint8, score int, staticEval int, rawEval int, bestMove Move) {
+	if bestMove.flag() >= capture || abs(score) >= MateScore-MaxSearchDepth ||
+		(flag == FlagBeta && score <= staticEval) || (flag == FlagAlpha && score >= staticEval) {
+		return
+	}
+
+	diff := score - rawEval
+	s.pawnCorrection.update(pos.PawnHash, pos.Turn, diff, depth)
+	s.materialCorrection.update(pos.nonPawnMaterialKey(), pos.Turn, diff, depth)
+}
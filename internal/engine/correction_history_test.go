@@ -0,0 +1,71 @@
+package engine
+
+import "testing"
+
+func TestCorrectionHistoryUpdateMovesTowardsTheDifference(t *testing.T) {
+	cht := CorrectionHistoryTable{}
+
+	for range 200 {
+		cht.update(12345, White, 40, 10)
+	}
+
+	expected := 40
+	got := cht.get(12345, White)
+
+	if got < expected-1 || got > expected {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+	if other := cht.get(12345, Black); other != 0 {
+		t.Errorf("Expected: %v, got: %v", 0, other)
+	}
+}
+
+func TestNonPawnMaterialKeyOnlyDependsOnMaterial(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString("4k3/8/3n4/8/8/2B5/8/4K3 w - - 0 1")
+	other := NewPosition()
+	other.LoadFromFenString("4k3/8/8/8/1n6/8/5B2/4K3 w - - 0 1")
+	withRook := NewPosition()
+	withRook.LoadFromFenString("4k3/8/3r4/8/8/2B5/8/4K3 w - - 0 1")
+
+	if pos.nonPawnMaterialKey() != other.nonPawnMaterialKey() {
+		t.Errorf("Expected: %v, got: %v", pos.nonPawnMaterialKey(), other.nonPawnMaterialKey())
+	}
+	if pos.nonPawnMaterialKey() == withRook.nonPawnMaterialKey() {
+		t.Errorf("Expected different keys, got: %v", withRook.nonPawnMaterialKey())
+	}
+}
+
+func TestCorrectEvalUsesCorrectionHistory(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString(StartingFenString)
+	s := NewSearch()
+
+	for range 200 {
+		s.updateCorrectionHistory(pos, 10, FlagExact, 60, 0, 0, NoMove)
+	}
+
+	expected := 59
+	got := s.correctEval(pos, 0)
+
+	if got < expected-1 || got > expected+1 {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
+
+func TestCorrectionHistoryIgnoresCapturesAndMateScores(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString(StartingFenString)
+	s := NewSearch()
+
+	s.updateCorrectionHistory(pos, 10, FlagExact, 60, 0, 0, *encodeMove(12, 28, capture))
+	s.updateCorrectionHistory(pos, 10, FlagExact, MateScore-5, 0, 0, NoMove)
+	s.updateCorrectionHistory(pos, 10, FlagBeta, -20, 0, 0, NoMove)
+
+	expected := 0
+	got := s.correctEval(pos, 0)
+
+	if got != expected {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
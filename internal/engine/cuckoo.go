@@ -0,0 +1,87 @@
+package engine
+
+// CuckooTableSize is the number of slots of the cuckoo tables, enough to hold the 3668 reversible moves
+const CuckooTableSize = 8192
+
+// cuckooKeys contains the zobrist key difference of every reversible move (a non pawn piece moving between
+// two squares on an empty board), stored with cuckoo hashing. Based on Marcel van Kervinck's paper
+// "The Cuckoo Table for Detecting Upcoming Repetitions"
+var cuckooKeys [CuckooTableSize]uint64
+
+// cuckooMoves contains the reversible move of each key stored in the cuckooKeys table
+var cuckooMoves [CuckooTableSize]Move
+
+// cuckooIndex returns the first slot of the key passed
+func cuckooIndex(key uint64) int {
+	return int(key & (CuckooTableSize - 1))
+}
+
+// cuckooAltIndex returns the second slot of the key passed
+func cuckooAltIndex(key uint64) int {
+	return int((key >> 16) & (CuckooTableSize - 1))
+}
+
+// generateCuckooTables returns the cuckoo tables with all the reversible moves. Must be called after the zobrist
+// keys and the attack tables are initialized
+func generateCuckooTables() (keys [CuckooTableSize]uint64, moves [CuckooTableSize]Move) {
+	for piece := WhiteKing; piece < NoPiece; piece++ {
+		if pieceRole(piece) == Pawn {
+			continue
+		}
+
+		for from := range 64 {
+			for to := from + 1; to < 64; to++ {
+				if Attacks(piece, bitboardFromIndex(from), 0)&bitboardFromIndex(to) == 0 {
+					continue
+				}
+
+				move := *encodeMove(uint16(from), uint16(to), quiet)
+				key := zobristHashKeys.getPieceSquareKey(piece, from) ^ zobristHashKeys.getPieceSquareKey(piece, to) ^
+					zobristHashKeys.getSideKey()
+
+				// Insert the move, kicking out the entry in the slot to its alternative slot until an empty one is found
+				i := cuckooIndex(key)
+				for {
+					keys[i], key = key, keys[i]
+					moves[i], move = move, moves[i]
+					if move == NoMove {
+						break
+					}
+					if i == cuckooIndex(key) {
+						i = cuckooAltIndex(key)
+					} else {
+						i = cuckooIndex(key)
+					}
+				}
+			}
+		}
+	}
+	return
+}
+
+// hasUpcomingRepetition returns if the side to move has a reversible move that leads to a position already
+// played after the root of the search (not the root itself), so the score of the position is at least a draw
+func (pos *Position) hasUpcomingRepetition(rootMoveCount int) bool {
+	moveCount := pos.positionHistory.moveCount
+	lastIrreversibleMove := max(moveCount-pos.halfmoveClock, rootMoveCount+1)
+	occupied := ^pos.EmptySquares()
+
+	for i := moveCount - 3; i >= lastIrreversibleMove; i -= 2 {
+		moveKey := pos.Hash ^ pos.positionHistory.previousPosition[i]
+
+		j := cuckooIndex(moveKey)
+		if cuckooKeys[j] != moveKey {
+			j = cuckooAltIndex(moveKey)
+			if cuckooKeys[j] != moveKey {
+				continue
+			}
+		}
+
+		move := cuckooMoves[j]
+		if squaresBetween[move.from()][move.to()]&occupied == 0 {
+			return true
+		}
+	}
+
+	return false
+}
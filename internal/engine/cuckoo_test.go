@@ -0,0 +1,73 @@
+package engine
+
+import "testing"
+
+func TestCuckooTablesHoldAllReversibleMoves(t *testing.T) {
+	got := 0
+	for _, move := range cuckooMoves {
+		if move != NoMove {
+			got++
+		}
+	}
+
+	expected := 3668
+	if got != expected {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
+
+func TestHasUpcomingRepetition(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString("5k2/6p1/pQ3p1p/1p6/4q3/2P3PK/1P5P/8 b - - 6 36")
+	pos.LoadMoves("e4f5", "h3g2", "f5e4", "g2h3", "e4f5") // h3g2 repeats the position after the first h3g2
+
+	expected := true
+	got := pos.hasUpcomingRepetition(0)
+
+	if got != expected {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
+
+func TestHasUpcomingRepetitionIgnoresPositionsBeforeTheRoot(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString("5k2/6p1/pQ3p1p/1p6/4q3/2P3PK/1P5P/8 b - - 6 36")
+	pos.LoadMoves("e4f5", "h3g2", "f5e4", "g2h3", "e4f5")
+
+	expected := false
+	got := pos.hasUpcomingRepetition(pos.positionHistory.moveCount)
+
+	if got != expected {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
+
+func TestHasUpcomingRepetitionNeedsAFreePath(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fen      string
+		expected bool
+	}{
+		{"free path", "4k3/8/8/8/8/R7/8/4K3 w - - 4 3", true},
+		{"blocked path", "4k3/8/8/8/8/R7/P7/4K3 w - - 4 3", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pos := NewPosition()
+			pos.LoadFromFenString(tc.fen)
+			// The position 3 plies back is the same with the rook on a1 and black to move, so Ra1 would repeat it
+			earlier := pos.Hash ^ zobristHashKeys.getPieceSquareKey(WhiteRook, 16) ^
+				zobristHashKeys.getPieceSquareKey(WhiteRook, 0) ^ zobristHashKeys.getSideKey()
+			for _, hash := range []uint64{0, earlier, 0, 0} {
+				pos.positionHistory.add(positionBefore(0), noCastling, hash)
+			}
+
+			got := pos.hasUpcomingRepetition(0)
+
+			if got != tc.expected {
+				t.Errorf("Expected: %v, got: %v", tc.expected, got)
+			}
+		})
+	}
+}
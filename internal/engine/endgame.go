@@ -0,0 +1,262 @@
+package engine
+
+// Scores of the specialized endgame evaluations
+const (
+	KnownWinBonus       = KnownWinScore / 2 // Bonus of the endings known to be won, below the scores of the endgame tables
+	KingToEdgeBonus     = 20                // Bonus by the distance of the weak king to the center
+	KingToCornerBonus   = 40                // Bonus by the proximity of the weak king to a corner of the bishop color
+	KingsProximityBonus = 10                // Bonus by the proximity of the kings
+
+	// ScaleNormal is the scale factor of the endgame score of the positions without a drawish ending
+	ScaleNormal = 64
+)
+
+// Drawish endings that scale down the endgame score, indexes of the EndgameScales
+const (
+	NoScaleTerm = iota - 1
+	OppositeBishopsTerm
+	RookVsBishopTerm
+	WrongBishopTerm
+	PawnlessMinorEdgeTerm
+)
+
+// endgameEvaluator is a specialized evaluation of an ending, with the side that has the advantage
+type endgameEvaluator struct {
+	evaluate func(pos *Position, strong Color) int
+	strong   Color
+}
+
+// endgameEvaluators contains the specialized evaluations by the material signature of the position
+var endgameEvaluators = map[uint64]endgameEvaluator{}
+
+func init() {
+	for _, strong := range []Color{White, Black} {
+		endgameEvaluators[materialSignature(pieceColor(Pawn, strong))] = endgameEvaluator{evaluateKPK, strong}
+		endgameEvaluators[materialSignature(pieceColor(Bishop, strong), pieceColor(Knight, strong))] = endgameEvaluator{evaluateKBNK, strong}
+	}
+}
+
+// materialSignature returns the key of the kings and the pieces passed. Each piece has 4 bits with its count
+func materialSignature(pieces ...int) (key uint64) {
+	key = 1<<(4*WhiteKing) | 1<<(4*BlackKing)
+	for _, piece := range pieces {
+		key += 1 << (4 * piece)
+	}
+	return
+}
+
+// positionSignature returns the material signature of the position
+func positionSignature(pos *Position) (key uint64) {
+	for piece, bb := range pos.Pieces {
+		key |= uint64(min(bb.count(), 15)) << (4 * piece)
+	}
+	return
+}
+
+// SpecializedEndgame returns if the position is scored by a specialized endgame evaluation
+func SpecializedEndgame(pos *Position) bool {
+	_, found := specializedEvaluator(pos)
+	return found
+}
+
+// specializedEvaluator returns the specialized evaluation of the material of the position, if there is one
+func specializedEvaluator(pos *Position) (endgameEvaluator, bool) {
+	if evaluator, found := endgameEvaluators[positionSignature(pos)]; found {
+		return evaluator, true
+	}
+
+	// KXK. Any material able to mate against a lone king
+	for _, strong := range []Color{White, Black} {
+		weak := strong.Opponent()
+		if pos.Sides[weak] != pos.Pieces[pieceColor(King, weak)] {
+			continue
+		}
+		bishops := pos.Pieces[pieceColor(Bishop, strong)].count()
+		if pos.Pieces[pieceColor(Queen, strong)]|pos.Pieces[pieceColor(Rook, strong)] > 0 ||
+			bishops >= 2 || bishops >= 1 && pos.Pieces[pieceColor(Knight, strong)] > 0 {
+			return endgameEvaluator{evaluateKXK, strong}, true
+		}
+	}
+	return endgameEvaluator{}, false
+}
+
+// evaluateEndgame returns the specialized evaluation of the position relative to the side to move, if there is one
+func evaluateEndgame(pos *Position) (int, bool) {
+	evaluator, found := specializedEvaluator(pos)
+	if !found {
+		return 0, false
+	}
+
+	score := evaluator.evaluate(pos, evaluator.strong)
+	if pos.Turn != evaluator.strong {
+		score = -score
+	}
+	return score, true
+}
+
+// evaluateKXK drives the weak king to the edge of the board, and the strong king close to it
+func evaluateKXK(pos *Position, strong Color) int {
+	weakKing := Bsf(pos.KingPosition(strong.Opponent()))
+	strongKing := Bsf(pos.KingPosition(strong))
+
+	score := KnownWinBonus + endgameMaterial(pos, strong)
+	score += KingToEdgeBonus * centerDistance(weakKing)
+	score += KingsProximityBonus * (7 - SquareDistance(strongKing, weakKing))
+	return score
+}
+
+// evaluateKBNK drives the weak king to a corner of the color of the bishop, where the mate is possible
+func evaluateKBNK(pos *Position, strong Color) int {
+	weakKing := Bsf(pos.KingPosition(strong.Opponent()))
+	strongKing := Bsf(pos.KingPosition(strong))
+	bishop := Bsf(pos.Pieces[pieceColor(Bishop, strong)])
+
+	corners := [2]int{0, 63} // Dark squares corners
+	if squareColor(bishop) != squareColor(0) {
+		corners = [2]int{7, 56}
+	}
+	cornerDistance := min(SquareDistance(weakKing, corners[0]), SquareDistance(weakKing, corners[1]))
+
+	score := KnownWinBonus + endgameMaterial(pos, strong)
+	score += KingToCornerBonus * (7 - cornerDistance)
+	score += KingsProximityBonus * (7 - SquareDistance(strongKing, weakKing))
+	return score
+}
+
+// evaluateKPK evaluates the king and pawn against king ending by the rule of the square and the key squares
+func evaluateKPK(pos *Position, strong Color) int {
+	weak := strong.Opponent()
+	pawn := Bsf(pos.Pieces[pieceColor(Pawn, strong)])
+	strongKing := Bsf(pos.KingPosition(strong))
+	weakKing := Bsf(pos.KingPosition(weak))
+
+	// Squares from the side of the pawn, promoting on the eighth rank
+	if strong == Black {
+		pawn, strongKing, weakKing = pawn^56, strongKing^56, weakKing^56
+	}
+	file, rank := pawn%8, pawn/8
+	promotion := 56 + file
+	score := EndgamePieceValue[Pawn] + PassedPawnsBonusEg[rank]
+
+	// Rook pawns are a draw when the weak king reaches the corner
+	if (file == 0 || file == 7) && SquareDistance(weakKing, promotion) <= 1 {
+		return 0
+	}
+
+	// Rule of the square. The weak king can't catch the pawn
+	pawnDistance := min(7-rank, 5)
+	weakDistance := SquareDistance(weakKing, promotion)
+	if pos.Turn == weak {
+		weakDistance--
+	}
+	blocked := strongKing%8 == file && strongKing > pawn
+	if pawnDistance < weakDistance && !blocked {
+		return score + KnownWinBonus
+	}
+
+	// Key squares. The strong king escorts the pawn to the promotion, unless the pawn is captured first
+	keyRanks := [2]int{min(rank+2, 7), min(rank+2, 7)}
+	if rank >= 4 {
+		keyRanks[0] = rank + 1
+	}
+	onKeySquare := abs(strongKing%8-file) <= 1 && (strongKing/8 == keyRanks[0] || strongKing/8 == keyRanks[1])
+	captured := pos.Turn == weak && SquareDistance(weakKing, pawn) == 1 && SquareDistance(strongKing, pawn) > 1
+	if file != 0 && file != 7 && onKeySquare && !captured {
+		return score + KnownWinBonus
+	}
+	return score
+}
+
+// ScaleTerm returns the drawish ending of the position that scales down its endgame score, or NoScaleTerm
+func ScaleTerm(pos *Position) int {
+	// The drawish endings have a side without pawns, or only bishops and pawns. Most of the positions have pawns
+	// on both sides and other pieces, so they are skipped before counting the material
+	others := pos.Pieces[WhiteQueen] | pos.Pieces[BlackQueen] | pos.Pieces[WhiteRook] | pos.Pieces[BlackRook] |
+		pos.Pieces[WhiteKnight] | pos.Pieces[BlackKnight]
+	if pos.Pieces[WhitePawn] > 0 && pos.Pieces[BlackPawn] > 0 && others > 0 {
+		return NoScaleTerm
+	}
+
+	strong := Color(White)
+	if endgameMaterial(pos, Black) > endgameMaterial(pos, White) {
+		strong = Black
+	}
+	weak := strong.Opponent()
+
+	var pieces [2][6]int
+	for piece, bb := range pos.Pieces {
+		pieces[piece/6][pieceRole(piece)] = bb.count()
+	}
+	minors := func(side Color) int { return pieces[side][Bishop] + pieces[side][Knight] }
+	majors := func(side Color) int { return pieces[side][Queen] + pieces[side][Rook] }
+
+	// Opposite colored bishops, with only pawns besides them
+	if pieces[White][Bishop] == 1 && pieces[Black][Bishop] == 1 && minors(White)+minors(Black) == 2 && majors(White)+majors(Black) == 0 &&
+		squareColor(Bsf(pos.Pieces[WhiteBishop])) != squareColor(Bsf(pos.Pieces[BlackBishop])) {
+		return OppositeBishopsTerm
+	}
+
+	// Rook against bishop, without pawns
+	if pos.Sides[strong] == pos.Pieces[pieceColor(King, strong)]|pos.Pieces[pieceColor(Rook, strong)] && pieces[strong][Rook] == 1 &&
+		pos.Sides[weak] == pos.Pieces[pieceColor(King, weak)]|pos.Pieces[pieceColor(Bishop, weak)] && pieces[weak][Bishop] == 1 {
+		return RookVsBishopTerm
+	}
+
+	// Bishops and rook pawns, when the bishops don't control the promotion square and the weak king is there
+	strongPawns := pos.Pieces[pieceColor(Pawn, strong)]
+	bishops := pos.Pieces[pieceColor(Bishop, strong)]
+	if strongPawns > 0 && bishops > 0 && pos.Sides[weak] == pos.Pieces[pieceColor(King, weak)] &&
+		pos.Sides[strong] == pos.Pieces[pieceColor(King, strong)]|bishops|strongPawns {
+		for _, file := range []int{0, 7} {
+			promotion := [2]int{56 + file, file}[strong]
+			sameColor := bishops&colorSquares[squareColor(promotion)] > 0
+			if strongPawns&^Files[file] == 0 && !sameColor && SquareDistance(Bsf(pos.KingPosition(weak)), promotion) <= 1 {
+				return WrongBishopTerm
+			}
+		}
+	}
+
+	// Pawnless endings where the strong side is a minor piece ahead or less
+	material := func(side Color) int {
+		return 9*pieces[side][Queen] + 5*pieces[side][Rook] + 3*minors(side)
+	}
+	if strongPawns == 0 && material(strong) > 0 && material(strong)-material(weak) <= 3 {
+		return PawnlessMinorEdgeTerm
+	}
+	return NoScaleTerm
+}
+
+// scaleFactor returns the scale factor of the endgame score of the position
+func scaleFactor(pos *Position) int {
+	if term := ScaleTerm(pos); term != NoScaleTerm {
+		return EndgameScales[term]
+	}
+	return ScaleNormal
+}
+
+// endgameMaterial returns the endgame value of the pieces of the side, without the king
+func endgameMaterial(pos *Position, side Color) (material int) {
+	for role := Queen; role <= Pawn; role++ {
+		material += EndgamePieceValue[role] * pos.Pieces[pieceColor(role, side)].count()
+	}
+	return
+}
+
+// SquareDistance returns the number of king moves between the squares
+func SquareDistance(from int, to int) int {
+	return max(abs(from%8-to%8), abs(from/8-to/8))
+}
+
+// centerDistance returns the number of files and ranks between the square and the center of the board
+func centerDistance(sq int) int {
+	file, rank := sq%8, sq/8
+	return 3 - min(file, 7-file) + 3 - min(rank, 7-rank)
+}
+
+// squareColor returns the color of the square. Zero for the dark squares, like a1
+func squareColor(sq int) int {
+	return (sq/8 + sq%8) % 2
+}
+
+// colorSquares are the bitboards of the dark and light squares, indexed by squareColor
+var colorSquares = [2]Bitboard{darkSquares, lightSquares}
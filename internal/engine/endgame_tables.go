@@ -0,0 +1,459 @@
+package engine
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// KnownWinScore is the static score of a won position in the endgame tables, reduced by the distance to mate
+const KnownWinScore = 10000
+
+// Tables to index the positions of the endgame tables
+var (
+	endgameSymmetries [8][64]int // Squares mirrored by each of the 8 symmetries of the board
+	kingSymmetries    [64][]int  // Symmetries that move the king on each square to the a1-d1-d4 triangle
+	triangleIndex     [64]int    // Maps the squares of the a1-d1-d4 triangle to 0-9, and the rest to -1
+	triangleSquares   [10]int
+)
+
+func init() {
+	n := 0
+	for sq := range 64 {
+		triangleIndex[sq] = -1
+		if sq%8 <= 3 && sq/8 <= sq%8 {
+			triangleIndex[sq] = n
+			triangleSquares[n] = sq
+			n++
+		}
+	}
+
+	for i := range endgameSymmetries {
+		for sq := range 64 {
+			mirrored := sq
+			if i&1 != 0 {
+				mirrored ^= 7
+			}
+			if i&2 != 0 {
+				mirrored ^= 56
+			}
+			if i&4 != 0 {
+				mirrored = mirrored>>3 | (mirrored&7)<<3
+			}
+			endgameSymmetries[i][sq] = mirrored
+			if triangleIndex[mirrored] >= 0 {
+				kingSymmetries[sq] = append(kingSymmetries[sq], i)
+			}
+		}
+	}
+}
+
+// EndgameTables contains the distance to mate tables of the endings of a king and some pieces against a lone king.
+// Each table is generated by retrograde analysis the first time a position of its material is probed
+type EndgameTables struct {
+	tables []*endgameTable
+}
+
+// endgameTable is the distance to mate table of a material set, with the pieces of the stronger side as white.
+// Positions are stored by the squares of the white king, the white pieces and the black king
+type endgameTable struct {
+	pieces     []int           // White pieces besides the king
+	n          int             // Number of pieces, including the kings
+	pawns      bool            // Pawn tables are only mirrored horizontally, and pawnless ones by the 8 symmetries
+	promotions []*endgameTable // Tables reached by promoting the pawn, that are needed to generate this one
+	dtm        [2][]uint8      // Plies to mate plus one, with white and black to move. Zero for draws
+	generated  sync.Once
+	loading    sync.Once
+	ready      atomic.Bool
+}
+
+// NewEndgameTables returns the tables of KQK, KRK, KPK and KBNK. The tables are not generated until probed
+func NewEndgameTables() *EndgameTables {
+	kqk := newEndgameTable(WhiteQueen)
+	krk := newEndgameTable(WhiteRook)
+	kpk := newEndgameTable(WhitePawn)
+	kpk.promotions = []*endgameTable{kqk, krk}
+	kbnk := newEndgameTable(WhiteBishop, WhiteKnight)
+
+	return &EndgameTables{tables: []*endgameTable{kqk, krk, kpk, kbnk}}
+}
+
+// newEndgameTable returns a new table for the white pieces passed against a lone king
+func newEndgameTable(pieces ...int) *endgameTable {
+	return &endgameTable{
+		pieces: pieces,
+		n:      len(pieces) + 2,
+		pawns:  pieces[0] == WhitePawn,
+	}
+}
+
+// endgameTables are the tables shared by all the searches
+var endgameTables = NewEndgameTables()
+
+// probe returns the result of the position for the side to move (1 for a win, 0 for a draw and -1 for a loss),
+// and the plies to mate. Tables not yet generated start their generation in the background and are not found, so
+// the search is never stopped to wait for them
+func (et *EndgameTables) probe(pos *Position) (wdl int, plies int, found bool) {
+	if et == nil || pos.castling.castlingRights != noCastling || pos.Sides[All].count() > 4 {
+		return 0, 0, false
+	}
+
+	strong := Color(White)
+	if pos.Sides[White] == pos.Pieces[WhiteKing] {
+		strong = Black
+	}
+	weak := strong.Opponent()
+	if pos.Sides[weak] != pos.KingPosition(weak) || pos.Sides[strong] == pos.KingPosition(strong) {
+		return 0, 0, false
+	}
+
+	for _, t := range et.tables {
+		if !t.matches(pos, strong) {
+			continue
+		}
+		if !t.ready.Load() {
+			t.loading.Do(func() { go t.ensure() })
+			return 0, 0, false
+		}
+
+		// Black as the stronger side is stored mirrored vertically, with the colors swapped
+		flip := 0
+		if strong == Black {
+			flip = 56
+		}
+		var sq [4]int
+		sq[0] = Bsf(pos.KingPosition(strong)) ^ flip
+		for i, piece := range t.pieces {
+			sq[i+1] = Bsf(pos.Pieces[pieceColor(pieceRole(piece), strong)]) ^ flip
+		}
+		sq[t.n-1] = Bsf(pos.KingPosition(weak)) ^ flip
+
+		side := 0
+		if pos.Turn != strong {
+			side = 1
+		}
+		value := int(t.dtm[side][t.index(sq)])
+
+		switch {
+		case value == 0:
+			return 0, 0, true
+		case side == 0:
+			return 1, value - 1, true
+		default:
+			return -1, value - 1, true
+		}
+	}
+	return 0, 0, false
+}
+
+// evaluate returns the static score of the position when it's found in the tables, with won positions
+// scored above any evaluation and closer mates scored higher
+func (et *EndgameTables) evaluate(pos *Position) (int, bool) {
+	wdl, plies, found := et.probe(pos)
+	if !found {
+		return 0, false
+	}
+	return wdl * (KnownWinScore - plies), true
+}
+
+// endgameScore returns the search score of the result found in the tables at the ply passed
+func endgameScore(wdl int, plies int, ply int) int {
+	return wdl * (MateScore - ply - plies)
+}
+
+// matches returns if the pieces of the side passed, besides the king, are the pieces of the table
+func (t *endgameTable) matches(pos *Position, strong Color) bool {
+	pieces := pos.Sides[strong] &^ pos.KingPosition(strong)
+	if pieces.count() != len(t.pieces) {
+		return false
+	}
+	for _, piece := range t.pieces {
+		if pos.Pieces[pieceColor(pieceRole(piece), strong)].count() != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// ensure generates the table if it has not been generated yet
+func (t *endgameTable) ensure() {
+	t.generated.Do(t.generate)
+	t.ready.Store(true)
+}
+
+// size returns the number of indexes of the table
+func (t *endgameTable) size() int {
+	size := 64
+	if !t.pawns {
+		size = 10
+	}
+	for range t.n - 1 {
+		size *= 64
+	}
+	return size
+}
+
+// index returns the index of the position of the squares passed. Pawnless positions are mirrored to have the
+// white king on the a1-d1-d4 triangle, choosing the lowest index when there is more than one symmetry
+func (t *endgameTable) index(sq [4]int) int {
+	if t.pawns {
+		if sq[1]%8 > 3 {
+			for i := range t.n {
+				sq[i] ^= 7
+			}
+		}
+		idx := sq[0]
+		for i := 1; i < t.n; i++ {
+			idx = idx*64 + sq[i]
+		}
+		return idx
+	}
+
+	best := -1
+	for _, i := range kingSymmetries[sq[0]] {
+		symmetry := &endgameSymmetries[i]
+		idx := triangleIndex[symmetry[sq[0]]]
+		for i := 1; i < t.n; i++ {
+			idx = idx*64 + symmetry[sq[i]]
+		}
+		if best < 0 || idx < best {
+			best = idx
+		}
+	}
+	return best
+}
+
+// squares returns the squares of the pieces of the position at the index passed
+func (t *endgameTable) squares(idx int) (sq [4]int) {
+	for i := t.n - 1; i > 0; i-- {
+		sq[i] = idx % 64
+		idx /= 64
+	}
+	sq[0] = idx
+	if !t.pawns {
+		sq[0] = triangleSquares[idx]
+	}
+	return
+}
+
+// setup places the pieces of the table on the squares passed, with the side to move passed. The position is
+// reused by the generation, so its previous pieces are removed
+func (t *endgameTable) setup(pos *Position, sq [4]int, turn Color) {
+	pos.Pieces = [12]Bitboard{}
+	pos.Sides = [3]Bitboard{}
+	pos.AddPiece(WhiteKing, sq[0])
+	for i, piece := range t.pieces {
+		pos.AddPiece(piece, sq[i+1])
+	}
+	pos.AddPiece(BlackKing, sq[t.n-1])
+	pos.Turn = turn
+}
+
+// legal returns if the position is legal, with the pieces on different squares, the kings not adjacent, no pawns
+// on the first and last ranks and the side not to move out of check
+func (t *endgameTable) legal(pos *Position) bool {
+	if pos.Sides[All].count() != t.n || kingAttacksTable[Bsf(pos.KingPosition(White))]&pos.KingPosition(Black) != 0 {
+		return false
+	}
+	if pos.Pieces[WhitePawn]&(Ranks[0]|Ranks[7]) != 0 {
+		return false
+	}
+	return !pos.Check(pos.Turn.Opponent())
+}
+
+// pendingPosition is a position with black to move not yet lost, and the range of its children on the list of
+// children of the generation
+type pendingPosition struct {
+	idx        int
+	start, end int32
+}
+
+// generate builds the table by retrograde analysis. Starting from the checkmates, positions where white can move
+// to a lost position for black are won, and positions where all the moves of black lead to won positions for
+// white are lost. Positions never reached are draws. The moves of black are generated once and kept, while the
+// positions of white are found by undoing the moves of its pieces from the lost positions of black
+func (t *endgameTable) generate() {
+	for _, promotion := range t.promotions {
+		promotion.ensure()
+	}
+
+	size := t.size()
+	t.dtm = [2][]uint8{make([]uint8, size), make([]uint8, size)}
+	pos := NewPosition()
+	pos.castling.castlingRights = noCastling
+	pending := []pendingPosition{}
+	children := []int32{} // Indexes of the positions reached by the moves of black on the pending positions
+	lost := []int{}
+	seeds := map[int][]int{} // Won positions by promoting the pawn, by their plies to mate
+	lastSeed := 0
+
+	for idx := range size {
+		sq := t.squares(idx)
+		if t.index(sq) != idx {
+			continue
+		}
+
+		t.setup(pos, sq, Black)
+		if t.legal(pos) {
+			moves, draw := t.blackMoves(pos, sq)
+			switch {
+			case draw:
+			case len(moves) > 0:
+				start := int32(len(children))
+				for _, child := range moves {
+					children = append(children, int32(child))
+				}
+				pending = append(pending, pendingPosition{idx: idx, start: start, end: int32(len(children))})
+			case pos.Check(Black):
+				t.dtm[1][idx] = 1
+				lost = append(lost, idx)
+			}
+		}
+
+		pos.Turn = White
+		if len(t.promotions) > 0 && t.legal(pos) {
+			for _, value := range t.promotionValues(pos, sq) {
+				seeds[value] = append(seeds[value], idx)
+				lastSeed = max(lastSeed, value)
+			}
+		}
+	}
+
+	for ply := 1; len(lost) > 0 || ply <= lastSeed; ply += 2 {
+		won := 0
+		for _, idx := range seeds[ply] {
+			if t.dtm[0][idx] == 0 {
+				t.dtm[0][idx] = uint8(ply + 1)
+				won++
+			}
+		}
+		for _, idx := range lost {
+			for _, previous := range t.whiteUnmoves(pos, t.squares(idx)) {
+				if t.dtm[0][previous] == 0 {
+					t.dtm[0][previous] = uint8(ply + 1)
+					won++
+				}
+			}
+		}
+
+		lost = lost[:0]
+		if won == 0 {
+			continue
+		}
+		remaining := pending[:0]
+		for _, p := range pending {
+			if t.allWon(children[p.start:p.end]) {
+				t.dtm[1][p.idx] = uint8(ply + 2)
+				lost = append(lost, p.idx)
+			} else {
+				remaining = append(remaining, p)
+			}
+		}
+		pending = remaining
+	}
+}
+
+// allWon returns if all the positions with white to move of the indexes passed are won
+func (t *endgameTable) allWon(indexes []int32) bool {
+	for _, idx := range indexes {
+		if t.dtm[0][idx] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// blackMoves returns the indexes of the positions reached by the legal moves of black on the position, and if
+// black can capture a piece, which draws the game
+func (t *endgameTable) blackMoves(pos *Position, sq [4]int) (children []int, draw bool) {
+	ml := legalMoves(pos)
+	for _, move := range ml.moves[:ml.length] {
+		if move.flag() == capture {
+			return nil, true
+		}
+		child := sq
+		child[t.n-1] = move.to()
+		children = append(children, t.index(child))
+	}
+	return children, false
+}
+
+// promotionValues returns the values on the promotion tables of the positions reached by promoting the pawn to a
+// queen or a rook on the position with white to move
+func (t *endgameTable) promotionValues(pos *Position, sq [4]int) (values []int) {
+	if pos.Pieces[WhitePawn]&Ranks[6] == 0 {
+		return
+	}
+	ml := legalMoves(pos)
+	for _, move := range ml.moves[:ml.length] {
+		piece := NoPiece
+		switch move.flag() {
+		case queenPromotion:
+			piece = WhiteQueen
+		case rookPromotion:
+			piece = WhiteRook
+		}
+		for _, promotion := range t.promotions {
+			if promotion.pieces[0] != piece {
+				continue
+			}
+			child := sq
+			child[1] = move.to()
+			if value := int(promotion.dtm[1][promotion.index(child)]); value > 0 {
+				values = append(values, value)
+			}
+		}
+	}
+	return
+}
+
+// whiteUnmoves returns the indexes of the positions with white to move where a white move leads to the position
+// passed. The quiet moves of the pieces are reversible, so their origins are the quiet moves of the pieces on the
+// position, while the pawns come from the squares behind them
+func (t *endgameTable) whiteUnmoves(pos *Position, sq [4]int) (previous []int) {
+	t.setup(pos, sq, White)
+	pd := pos.generatePositionData()
+	ml := NewMoveList()
+	pos.generateQuiets(ml, &pd)
+
+	for _, move := range ml.moves[:ml.length] {
+		i := 0
+		for sq[i] != move.from() {
+			i++
+		}
+		if i > 0 && t.pieces[i-1] == WhitePawn {
+			continue
+		}
+
+		pos.MakeMove(&move)
+		check := pos.Check(Black)
+		pos.UnmakeMove(&move)
+		if !check {
+			parent := sq
+			parent[i] = move.to()
+			previous = append(previous, t.index(parent))
+		}
+	}
+
+	for i, piece := range t.pieces {
+		if piece != WhitePawn {
+			continue
+		}
+		pawn := sq[i+1]
+		origins := []int{}
+		if pawn >= 16 && pos.Sides[All]&bitboardFromIndex(pawn-8) == 0 {
+			origins = append(origins, pawn-8)
+			if pawn/8 == 3 && pos.Sides[All]&bitboardFromIndex(pawn-16) == 0 {
+				origins = append(origins, pawn-16)
+			}
+		}
+		for _, origin := range origins {
+			parent := sq
+			parent[i+1] = origin
+			t.setup(pos, parent, White)
+			if !pos.Check(Black) {
+				previous = append(previous, t.index(parent))
+			}
+		}
+	}
+	return
+}
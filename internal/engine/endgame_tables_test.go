@@ -0,0 +1,136 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+// endgamePosition returns the position of the table with the squares and side to move passed
+func endgamePosition(t *endgameTable, sq [4]int, whiteToMove bool) *Position {
+	pos := NewPosition()
+	pos.castling.castlingRights = noCastling
+	turn := Color(White)
+	if !whiteToMove {
+		turn = Black
+	}
+	t.setup(pos, sq, turn)
+	return pos
+}
+
+func TestEndgameTablesAreConsistentWithTheMoves(t *testing.T) {
+	tables := NewEndgameTables()
+
+	// The value of each position must be the best value of the positions after its legal moves
+	for _, table := range tables.tables {
+		table.ensure()
+		pos := endgamePosition(table, table.squares(0), true)
+		for idx := range table.size() {
+			sq := table.squares(idx)
+			if table.index(sq) != idx {
+				continue
+			}
+			for _, turn := range []Color{White, Black} {
+				table.setup(pos, sq, turn)
+				if !table.legal(pos) {
+					continue
+				}
+
+				expected := [2]int{0, 0}
+				ml := legalMoves(pos)
+				if ml.length == 0 && pos.Check(pos.Turn) {
+					expected = [2]int{-1, 0}
+				}
+				best := -MateScore
+				for _, move := range ml.moves[:ml.length] {
+					pos.MakeMove(&move)
+					wdl, plies, _ := tables.probe(pos)
+					pos.UnmakeMove(&move)
+
+					if score := endgameScore(-wdl, plies+1, 0); score > best {
+						best = score
+						expected = [2]int{-wdl, (plies + 1) * wdl * wdl}
+					}
+				}
+
+				wdl, plies, found := tables.probe(pos)
+				if got := [2]int{wdl, plies}; !found || got != expected {
+					t.Fatalf("Expected: %v, got: %v at %v", expected, got, pos.ToFen())
+				}
+			}
+		}
+	}
+}
+
+func TestProbeEndgameTables(t *testing.T) {
+	endgameTables.tables[0].ensure()
+	testCases := []struct {
+		fen      string
+		expected [2]int
+	}{
+		{"k7/8/1K6/8/8/8/2Q5/8 w - - 0 1", [2]int{1, 1}},  // Qc8#
+		{"k7/8/1K6/8/8/8/2Q5/8 b - - 0 1", [2]int{-1, 4}}, // Kb8 Qc7+ Ka8 Qa7#
+		{"8/2q5/8/8/8/1k6/8/K7 b - - 0 1", [2]int{1, 1}},  // Same position with the colors swapped
+		{"k7/2Q5/1K6/8/8/8/8/8 b - - 0 1", [2]int{0, 0}},  // Stalemate
+	}
+
+	for _, tc := range testCases {
+		pos := NewPosition()
+		pos.LoadFromFenString(tc.fen)
+
+		wdl, plies, found := endgameTables.probe(pos)
+
+		if got := [2]int{wdl, plies}; !found || got != tc.expected {
+			t.Errorf("Expected: %v, got: %v (%v) at %v", tc.expected, got, found, tc.fen)
+		}
+	}
+}
+
+func TestEvaluateEndgameTables(t *testing.T) {
+	endgameTables.tables[3].ensure()
+	pos := NewPosition()
+	pos.LoadFromFenString("8/8/8/8/8/2k5/8/KBN5 b - - 0 1")
+	_, plies, _ := endgameTables.probe(pos)
+	ev := NewEvaluation(DefaultPawnHashTableSizeInMb)
+	ev.Endgames = endgameTables
+
+	expected := -KnownWinScore + plies
+	got := ev.Evaluate(pos)
+
+	if got != expected {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
+
+func TestSearchPlaysTheEndgameTables(t *testing.T) {
+	endgameTables.tables[3].ensure()
+	pos := NewPosition()
+	pos.LoadFromFenString("8/8/8/8/8/2k5/8/KBN5 w - - 0 1")
+	wdl, plies, _ := endgameTables.probe(pos)
+	s := NewSearch()
+	s.TimeControl.Initialize(DepthStrategy, int(pos.Turn), pos.FullMoveNumber, Clock{})
+
+	stdout := make(chan string, 1000)
+	score, _ := s.IterativeDeepening(pos, 2, stdout)
+
+	expected := endgameScore(wdl, plies, 0)
+	if wdl != 1 || score != expected {
+		t.Errorf("Expected: %v, got: %v", expected, score)
+	}
+}
+
+func TestTimedSearchDoesNotWaitForTheEndgameTables(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString("8/8/8/3k4/8/8/p7/KBN5 w - - 0 1") // Kxa2 leads to KBNK
+	s := NewSearch()
+	s.Evaluation.Endgames = NewEndgameTables()
+	s.TimeControl.Initialize(MoveTimeStrategy, int(pos.Turn), pos.FullMoveNumber, Clock{moveTime: 200})
+
+	stdout := make(chan string, 1000)
+	start := time.Now()
+	s.IterativeDeepening(pos, MaxSearchDepth, stdout)
+
+	// Generating KBNK takes seconds, so the search must finish while it's generated in the background
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected: %v, got: %v", "less than 1s", elapsed)
+	}
+}
@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"testing"
+)
+
+func TestScaleTerm(t *testing.T) {
+	testCases := []struct {
+		fen      string
+		expected int
+	}{
+		{"8/5k2/4bp2/8/3B4/5P2/5K2/8 w - - 0 1", OppositeBishopsTerm},
+		{"8/5k2/4bp2/8/2B5/5P2/5K2/8 w - - 0 1", NoScaleTerm}, // Same colored bishops
+		{"8/8/3k4/8/2b5/8/4R3/4K3 b - - 0 1", RookVsBishopTerm},
+		{"k7/8/1K6/P7/8/8/8/2B5 w - - 0 1", WrongBishopTerm},
+		{"k7/8/1K6/P7/8/8/8/3B4 w - - 0 1", NoScaleTerm}, // The bishop controls the promotion square
+		{"8/3k4/8/3n4/8/3N4/2BK4/8 w - - 0 1", PawnlessMinorEdgeTerm},
+		{"8/3k4/8/3n4/8/3N4/2BK1P2/8 w - - 0 1", NoScaleTerm},
+	}
+
+	for _, tc := range testCases {
+		pos := NewPosition()
+		pos.LoadFromFenString(tc.fen)
+
+		got := ScaleTerm(pos)
+
+		if got != tc.expected {
+			t.Errorf("Expected: %v, got: %v at %v", tc.expected, got, tc.fen)
+		}
+	}
+}
+
+func TestScaleFactorReducesTheEndgameScore(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString("8/8/3k4/8/2b5/8/4R3/4K3 w - - 0 1")
+	ev := NewEvaluation(DefaultPawnHashTableSizeInMb)
+
+	score := ev.Evaluate(pos)
+	ev.Eval.scale = ScaleNormal
+	unscaled := ev.Eval.score(pos.Turn)
+
+	if score <= 0 || score >= unscaled {
+		t.Errorf("Expected: %v, got: %v", "a lower positive score", score)
+	}
+}
+
+func TestEvaluateKXKDrivesTheKingToTheEdge(t *testing.T) {
+	ev := NewEvaluation(DefaultPawnHashTableSizeInMb)
+	center, edge := NewPosition(), NewPosition()
+	center.LoadFromFenString("8/8/8/3k4/8/8/8/R3K3 w - - 0 1")
+	edge.LoadFromFenString("3k4/8/8/8/8/8/8/R3K3 w - - 0 1")
+
+	centerScore, edgeScore := ev.Evaluate(center), ev.Evaluate(edge)
+
+	if centerScore < KnownWinBonus || edgeScore <= centerScore {
+		t.Errorf("Expected: %v, got: %v and %v", "a known win, higher on the edge", centerScore, edgeScore)
+	}
+}
+
+func TestEvaluateKBNKDrivesTheKingToTheBishopCorner(t *testing.T) {
+	ev := NewEvaluation(DefaultPawnHashTableSizeInMb)
+	right, wrong := NewPosition(), NewPosition()
+	right.LoadFromFenString("k7/8/2K5/8/8/8/8/3BN3 b - - 0 1") // Light squared bishop, king on a8
+	wrong.LoadFromFenString("7k/8/5K2/8/8/8/8/3BN3 b - - 0 1")
+
+	rightScore, wrongScore := ev.Evaluate(right), ev.Evaluate(wrong)
+
+	if rightScore >= wrongScore {
+		t.Errorf("Expected: %v, got: %v and %v", "a lower score on the bishop corner", rightScore, wrongScore)
+	}
+}
+
+func TestEvaluateKPK(t *testing.T) {
+	testCases := []struct {
+		fen      string
+		expected bool // Known win for the side with the pawn
+	}{
+		{"8/8/8/P7/8/8/8/k3K3 w - - 0 1", true},   // Outside the square of the pawn
+		{"8/8/8/P1k5/8/8/8/4K3 w - - 0 1", false}, // Inside the square
+		{"8/8/3k4/8/3KP3/8/8/8 w - - 0 1", false}, // No key square
+		{"8/8/3k4/3K4/8/4P3/8/8 b - - 0 1", true}, // King on a key square
+		{"k7/8/8/P7/8/8/8/4K3 w - - 0 1", false},  // Rook pawn with the king on the corner
+		{"8/8/8/8/4k3/8/7p/K7 b - - 0 1", true},   // Black pawn
+	}
+
+	for _, tc := range testCases {
+		pos := NewPosition()
+		pos.LoadFromFenString(tc.fen)
+		strong := Color(White)
+		if pos.Pieces[BlackPawn] > 0 {
+			strong = Black
+		}
+
+		got := evaluateKPK(pos, strong) > KnownWinBonus
+
+		if got != tc.expected {
+			t.Errorf("Expected: %v, got: %v at %v", tc.expected, got, tc.fen)
+		}
+	}
+}
+
+func TestColorSquares(t *testing.T) {
+	for sq := range 64 {
+		expected := squareColor(sq)
+		got := 1
+		if colorSquares[0]&bitboardFromIndex(sq) > 0 {
+			got = 0
+		}
+
+		if got != expected || colorSquares[0]&colorSquares[1] != 0 {
+			t.Errorf("Expected: %v, got: %v at %v", expected, got, sq)
+		}
+	}
+}
@@ -3,7 +3,8 @@ package engine
 // Engine represents a chess engine
 type Engine struct {
 	Pos         Position
-	Search      Search
+	Search      *Search
+	Controller  *SearchController
 	TimeControl TimeControl
 	OpeningBook PolyglotBook
 	Options     Options
@@ -13,15 +14,18 @@ type Engine struct {
 type Options struct {
 	UseOpeningBook bool
 	Chess960       bool
+	Ponder         bool
 }
 
 // NewEngine returns a new Engine instance
 func NewEngine() *Engine {
 	pos := NewPosition()
 	pos.LoadFromFenString(StartingFenString)
+	search := NewSearch()
 	return &Engine{
 		Pos:         *pos,
-		Search:      *NewSearch(),
+		Search:      search,
+		Controller:  NewSearchController(search),
 		OpeningBook: PolyglotBook{},
 		Options: Options{
 			UseOpeningBook: false,
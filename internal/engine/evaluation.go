@@ -13,6 +13,17 @@ const (
 	ConnectedPawnBonusMg  = 7
 	ConnectedPawnBonusEg  = 4
 
+	// Passed Pawns, by the weight of their rank
+	PassedPawnFreePathBonusMg       = 4
+	PassedPawnFreePathBonusEg       = 12
+	PassedPawnAttackedPathPenaltyMg = -3
+	PassedPawnAttackedPathPenaltyEg = -8
+	PassedPawnOwnKingDistanceEg     = -3
+	PassedPawnEnemyKingDistanceEg   = 6
+	UnstoppablePassedPawnBonusEg    = 300
+	RookBehindPassedPawnBonusMg     = 5
+	RookBehindPassedPawnBonusEg     = 15
+
 	// Material Adjustment
 	BishopPairBonusMg    = 24
 	BishopPairBonusEg    = 64
@@ -79,6 +90,9 @@ var (
 	PassedPawnsBonusMg = [8]int{0, 0, -6, -8, 16, 0, 14, 0}
 	PassedPawnsBonusEg = [8]int{0, 10, 15, 42, 70, 141, 123, 0}
 
+	// EndgameScales contains the scale factors of the endgame score of the drawish endings, over ScaleNormal
+	EndgameScales = [4]int{32, 16, 0, 12}
+
 	// PawnShieldFrontBonus/PawnShieldSideBonus contains the bonus for pawns on the front and side ofthe king file(s)
 	PawnShieldFrontBonus = [4]int{0, 21, 16, 2}
 	PawnShieldSideBonus  = [4]int{21, 15, 7, 0}
@@ -99,6 +113,8 @@ type Evaluation struct {
 	Eval      EvalVector
 	EvalData  EvalData
 	PawnCache PawnHashTable
+	Endgames  *EndgameTables // Distance to mate tables of the basic endings. Nil when not used
+	Network   *Network       // Network that evaluates the positions (UseNNUE). Nil for the hand crafted evaluation
 }
 
 // EvalVector contains the different evaluation elements of a position
@@ -109,12 +125,15 @@ type EvalVector struct {
 	egMobility         [2]int
 	mgPawnStrucutre    [2]int
 	egPawnStructure    [2]int
+	mgPassedPawns      [2]int
+	egPassedPawns      [2]int
 	mgKingSafety       [2]int
 	mgThreats          [2]int
 	egThreats          [2]int
 	kingAttackersCount [2]int
 	kingAttacksWeight  [2]int
 	phase              int
+	scale              int // Scale factor of the endgame score
 }
 
 // EvalData contains positional data about the current position
@@ -152,11 +171,14 @@ func (ev *EvalVector) clear() {
 	ev.mgKingSafety = [2]int{0, 0}
 	ev.mgPawnStrucutre = [2]int{0, 0}
 	ev.egPawnStructure = [2]int{0, 0}
+	ev.mgPassedPawns = [2]int{0, 0}
+	ev.egPassedPawns = [2]int{0, 0}
 	ev.mgThreats = [2]int{0, 0}
 	ev.egThreats = [2]int{0, 0}
 	ev.kingAttackersCount = [2]int{0, 0}
 	ev.kingAttacksWeight = [2]int{0, 0}
 	ev.phase = 0
+	ev.scale = ScaleNormal
 }
 
 // clear clears the EvalData
@@ -193,10 +215,30 @@ func (ed *EvalData) init(pos *Position) {
 
 // Evaluate returns the static score of the position
 func (ev *Evaluation) Evaluate(pos *Position) int {
+	// Endings in the endgame tables are scored by their distance to mate
+	if score, found := ev.Endgames.evaluate(pos); found {
+		return score
+	}
+
+	// Endings with a specialized evaluation
+	if score, found := evaluateEndgame(pos); found {
+		return score
+	}
+
+	if ev.Network != nil {
+		return ev.evaluateNNUE(pos)
+	}
+
+	return ev.evaluateTerms(pos)
+}
+
+// evaluateTerms returns the hand crafted evaluation of the position, relative to the side to move
+func (ev *Evaluation) evaluateTerms(pos *Position) int {
 	ev.Eval.clear()
 	ev.EvalData.init(pos)
 
 	ev.evaluatePawns(pos)
+	ev.evaluatePassedPawns(pos)
 
 	for piece, bb := range pos.Pieces {
 		color := Color(piece / 6)
@@ -252,6 +294,8 @@ func (ev *Evaluation) Evaluate(pos *Position) int {
 	ev.Eval.mgMaterial[pos.Turn] += TempoBonus
 	ev.Eval.egMaterial[pos.Turn] += TempoBonus
 
+	ev.Eval.scale = scaleFactor(pos)
+
 	return ev.Eval.score(pos.Turn)
 }
 
@@ -265,13 +309,15 @@ func (ev *EvalVector) score(side Color) int {
 	eg += ev.egMobility[side] - ev.egMobility[opponent]
 	mg += ev.mgPawnStrucutre[side] - ev.mgPawnStrucutre[opponent]
 	eg += ev.egPawnStructure[side] - ev.egPawnStructure[opponent]
+	mg += ev.mgPassedPawns[side] - ev.mgPassedPawns[opponent]
+	eg += ev.egPassedPawns[side] - ev.egPassedPawns[opponent]
 	mg += ev.mgKingSafety[side] - ev.mgKingSafety[opponent]
 	mg += ev.mgThreats[side] - ev.mgThreats[opponent]
 	eg += ev.egThreats[side] - ev.egThreats[opponent]
 
 	mgPhase := min(ev.phase, 62)
 	egPhase := 62 - mgPhase
-	return (mg*mgPhase + eg*egPhase) / 62
+	return (mg*mgPhase*ScaleNormal + eg*egPhase*ev.scale) / (62 * ScaleNormal)
 }
 
 // evaluateKing evaluates the score of a king
@@ -605,6 +651,74 @@ func (ev *Evaluation) evaluatePawns(pos *Position) {
 
 }
 
+// evaluatePassedPawns evaluates the passed pawns by the position of the pieces. Unlike the pawn structure, these
+// terms are not stored on the pawn cache
+func (ev *Evaluation) evaluatePassedPawns(pos *Position) {
+	forward := [2]int{North, South}
+	backward := [2]int{South, North}
+
+	for side := Color(White); side <= Black; side++ {
+		opponent := side.Opponent()
+		passedPawns := PassedPawns(ev.EvalData.pawns[side], ev.EvalData.pawns[opponent], side)
+		if passedPawns == 0 {
+			continue
+		}
+
+		enemyAttacks := AttackedSquares(pos, opponent)
+		ownKing, enemyKing := Bsf(ev.EvalData.kings[side]), Bsf(ev.EvalData.kings[opponent])
+		loneKing := pos.Sides[opponent] == ev.EvalData.kings[opponent]|ev.EvalData.pawns[opponent]
+
+		for passedPawns > 0 {
+			fromBB := passedPawns.NextBit()
+			from := Bsf(fromBB)
+			rank := from / 8
+			if side == Black {
+				rank = 7 - rank
+			}
+			path := RayAttacks[forward[side]][from]
+			weight := PassedPawnWeight(rank)
+
+			// Unstoppable. The enemy king without pieces can't reach the promotion square
+			if loneKing && path&ev.EvalData.blocks == 0 {
+				promotion := Bsf(path & (Ranks[0] | Ranks[7]))
+				kingDistance := SquareDistance(enemyKing, promotion)
+				if pos.Turn == opponent {
+					kingDistance--
+				}
+				if min(7-rank, 5) < kingDistance {
+					ev.Eval.egPassedPawns[side] += UnstoppablePassedPawnBonusEg
+				}
+			}
+
+			if path&ev.EvalData.blocks == 0 {
+				ev.Eval.mgPassedPawns[side] += PassedPawnFreePathBonusMg * weight
+				ev.Eval.egPassedPawns[side] += PassedPawnFreePathBonusEg * weight
+			}
+			if path&enemyAttacks > 0 {
+				ev.Eval.mgPassedPawns[side] += PassedPawnAttackedPathPenaltyMg * weight
+				ev.Eval.egPassedPawns[side] += PassedPawnAttackedPathPenaltyEg * weight
+			}
+
+			// Kings proximity to the stop square
+			stop := Bsf(path & (fromBB<<8 | fromBB>>8))
+			ev.Eval.egPassedPawns[side] += PassedPawnOwnKingDistanceEg * SquareDistance(ownKing, stop) * weight
+			ev.Eval.egPassedPawns[side] += PassedPawnEnemyKingDistanceEg * SquareDistance(enemyKing, stop) * weight
+
+			// Rook behind the passed pawn, defending it on its way or attacking it
+			rookAttacks := Attacks(pieceColor(Rook, side), fromBB, ev.EvalData.blocks) & RayAttacks[backward[side]][from]
+			if rookAttacks&pos.Pieces[pieceColor(Rook, side)] > 0 {
+				ev.Eval.mgPassedPawns[side] += RookBehindPassedPawnBonusMg
+				ev.Eval.egPassedPawns[side] += RookBehindPassedPawnBonusEg
+			}
+		}
+	}
+}
+
+// PassedPawnWeight returns the weight of the dynamic terms of a passed pawn on the rank passed, relative to its side
+func PassedPawnWeight(rank int) int {
+	return max(rank-2, 0)
+}
+
 // OutpostSquares returns a bitboard of outpost squares for the given side
 // An outpost square is:
 // - In enemy territory (rank 4-6 for white, 3-5 for black)
@@ -197,3 +197,37 @@ func TestPawnStormPlusShield(t *testing.T) {
 		t.Errorf("Expected: %v, got: %v", expected, got)
 	}
 }
+
+func TestUnstoppablePassedPawn(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString("8/6k1/8/1P6/8/8/5PPK/8 w - - 0 1")
+	ev := NewEvaluation(DefaultPawnHashTableSizeInMb)
+	ev.EvalData.init(pos)
+
+	ev.evaluatePassedPawns(pos)
+
+	got := ev.Eval.egPassedPawns[White]
+	weight := PassedPawnWeight(4)
+	expected := UnstoppablePassedPawnBonusEg + PassedPawnFreePathBonusEg*weight +
+		PassedPawnOwnKingDistanceEg*6*weight + PassedPawnEnemyKingDistanceEg*5*weight
+
+	if got != expected {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
+
+func TestRookBehindPassedPawn(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString("3r2k1/5ppp/8/3P4/8/8/5PPP/3R2K1 w - - 0 1")
+	ev := NewEvaluation(DefaultPawnHashTableSizeInMb)
+	ev.EvalData.init(pos)
+
+	ev.evaluatePassedPawns(pos)
+
+	got := ev.Eval.mgPassedPawns[White]
+	expected := RookBehindPassedPawnBonusMg + PassedPawnAttackedPathPenaltyMg*PassedPawnWeight(4)
+
+	if got != expected {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
@@ -0,0 +1,129 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EvalTerm contains the middlegame and endgame score of a term of the evaluation for each side
+type EvalTerm struct {
+	Name string
+	Mg   [2]int
+	Eg   [2]int
+}
+
+// EvalTrace contains the terms of the hand crafted evaluation of a position
+type EvalTrace struct {
+	Terms []EvalTerm
+	Phase int   // Middlegame phase, from 0 (endgame) to 62 (middlegame)
+	Scale int   // Scale factor of the endgame score, over ScaleNormal
+	Mg    int   // Middlegame score from the white side
+	Eg    int   // Endgame score from the white side
+	Total int   // Tapered score from the white side
+	Score int   // Tapered score relative to the side to move
+	Turn  Color // Side to move
+}
+
+// Trace returns the terms of the hand crafted evaluation of the position, even when it's scored by the endgame
+// evaluations. The pawn structure is evaluated without the pawn cache, so both sides have their own scores
+func Trace(pos *Position) EvalTrace {
+	ev := Evaluation{PawnCache: *NewPawnHashTable(1)}
+	score := ev.evaluateTerms(pos)
+	vector := &ev.Eval
+
+	material, psqt := EvalTerm{Name: "Material"}, EvalTerm{Name: "PSQT"}
+	pawnMaterial, pawnPsqt := EvalTerm{}, EvalTerm{}
+	for piece, bb := range pos.Pieces {
+		side, role := piece/6, pieceRole(piece)
+		for bb > 0 {
+			sq := Bsf(bb.NextBit())
+			mgPsqt := middlegamePiecesScore[piece][sq] - MiddlegamePieceValue[role]
+			egPsqt := endgamePiecesScore[piece][sq] - EndgamePieceValue[role]
+
+			psqt.Mg[side] += mgPsqt
+			psqt.Eg[side] += egPsqt
+			if role == Pawn {
+				pawnMaterial.Mg[side] += MiddlegamePieceValue[role]
+				pawnMaterial.Eg[side] += EndgamePieceValue[role]
+				pawnPsqt.Mg[side] += mgPsqt
+				pawnPsqt.Eg[side] += egPsqt
+			}
+			if role != King {
+				material.Mg[side] += MiddlegamePieceValue[role]
+				material.Eg[side] += EndgamePieceValue[role]
+			}
+		}
+	}
+	for side := range 2 {
+		if pos.Pieces[pieceColor(Bishop, Color(side))].count() >= 2 {
+			material.Mg[side] += BishopPairBonusMg
+			material.Eg[side] += BishopPairBonusEg
+		}
+	}
+
+	tempo := EvalTerm{Name: "Tempo"}
+	tempo.Mg[pos.Turn], tempo.Eg[pos.Turn] = TempoBonus, TempoBonus
+
+	// The rest of the material score of the evaluation are the bonuses of the pieces (outposts and rooks on files)
+	pieces := EvalTerm{Name: "Pieces"}
+	pawnStructure := EvalTerm{Name: "Pawn structure"}
+	for side := range 2 {
+		kingMg, kingEg := MiddlegamePieceValue[King], EndgamePieceValue[King]
+		pieces.Mg[side] = vector.mgMaterial[side] - kingMg - material.Mg[side] - (psqt.Mg[side] - pawnPsqt.Mg[side]) - tempo.Mg[side] + pawnMaterial.Mg[side]
+		pieces.Eg[side] = vector.egMaterial[side] - kingEg - material.Eg[side] - (psqt.Eg[side] - pawnPsqt.Eg[side]) - tempo.Eg[side] + pawnMaterial.Eg[side]
+		pawnStructure.Mg[side] = vector.mgPawnStrucutre[side] - pawnMaterial.Mg[side] - pawnPsqt.Mg[side]
+		pawnStructure.Eg[side] = vector.egPawnStructure[side] - pawnMaterial.Eg[side] - pawnPsqt.Eg[side]
+	}
+
+	trace := EvalTrace{
+		Terms: []EvalTerm{
+			material,
+			psqt,
+			pieces,
+			{Name: "Mobility", Mg: vector.mgMobility, Eg: vector.egMobility},
+			pawnStructure,
+			{Name: "Passed pawns", Mg: vector.mgPassedPawns, Eg: vector.egPassedPawns},
+			{Name: "King safety", Mg: vector.mgKingSafety},
+			{Name: "Threats", Mg: vector.mgThreats, Eg: vector.egThreats},
+			tempo,
+		},
+		Phase: min(vector.phase, 62),
+		Scale: vector.scale,
+		Score: score,
+		Turn:  pos.Turn,
+	}
+	for _, term := range trace.Terms {
+		trace.Mg += term.Mg[White] - term.Mg[Black]
+		trace.Eg += term.Eg[White] - term.Eg[Black]
+	}
+	trace.Total = (trace.Mg*trace.Phase*ScaleNormal + trace.Eg*(62-trace.Phase)*trace.Scale) / (62 * ScaleNormal)
+	return trace
+}
+
+// String returns the table of the terms of the evaluation
+func (trace EvalTrace) String() string {
+	var table strings.Builder
+	line := "     ---------------+---------------+---------------+---------------\n"
+
+	table.WriteString("\n          Term      |     White     |     Black     |     Total\n")
+	table.WriteString("                    |   MG     EG   |   MG     EG   |   MG     EG\n")
+	table.WriteString(line)
+	for _, term := range trace.Terms {
+		table.WriteString(fmt.Sprintf("%19s | %6s %6s | %6s %6s | %6s %6s\n", term.Name,
+			inPawns(term.Mg[White]), inPawns(term.Eg[White]), inPawns(term.Mg[Black]), inPawns(term.Eg[Black]),
+			inPawns(term.Mg[White]-term.Mg[Black]), inPawns(term.Eg[White]-term.Eg[Black])))
+	}
+	table.WriteString(line)
+	table.WriteString(fmt.Sprintf("%19s |               |               | %6s %6s\n\n", "Total", inPawns(trace.Mg), inPawns(trace.Eg)))
+
+	table.WriteString(fmt.Sprintf("Phase: %d/62 (middlegame weight)\n", trace.Phase))
+	table.WriteString(fmt.Sprintf("Endgame scale: %d/%d\n", trace.Scale, ScaleNormal))
+	table.WriteString(fmt.Sprintf("Tapered total: %s (white side)\n", inPawns(trace.Total)))
+	table.WriteString(fmt.Sprintf("Final evaluation: %s (%s side)", inPawns(trace.Score), [2]string{"white", "black"}[trace.Turn]))
+	return table.String()
+}
+
+// inPawns returns the score in pawns units
+func inPawns(score int) string {
+	return fmt.Sprintf("%+.2f", float64(score)/100)
+}
@@ -0,0 +1,30 @@
+package engine
+
+import (
+	"testing"
+)
+
+func TestTraceAddsUpToTheEvaluation(t *testing.T) {
+	fens := []string{
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1",
+		"r1bq1rk1/pp2bppp/2n1pn2/3p4/2PP4/2N1PN2/PP3PPP/R2QKB1R w KQ - 0 8",
+		"8/5pk1/6p1/3P4/1p6/1P4P1/5PK1/8 b - - 0 40",
+	}
+
+	for _, fen := range fens {
+		pos := NewPosition()
+		pos.LoadFromFenString(fen)
+		ev := NewEvaluation(1)
+
+		expected := ev.Evaluate(pos)
+		trace := Trace(pos)
+		got := trace.Total
+		if pos.Turn == Black {
+			got = -got
+		}
+
+		if got != expected || trace.Score != expected {
+			t.Errorf("Expected: %v, got: %v (%v) at %v", expected, got, trace.Score, fen)
+		}
+	}
+}
@@ -27,6 +27,10 @@ type MoveGenerator struct {
 	moves                      *MoveList
 	badCapLength               int // To track the number of bad captures
 	skipQuiets                 bool
+	excluded                   *movesSearched // Moves that should not be returned by the generator
+	excludedMove               Move           // Move excluded from the search (singular extensions)
+	continuations              continuations  // Continuation histories used to score quiet moves
+	allowed                    *movesSearched // If not empty, only these moves are returned by the generator
 }
 
 // NewMoveGenerator returns a new move generator
@@ -46,8 +50,27 @@ func NewMoveGenerator(pos *Position, hashMove *Move, killer1 *Move, killer2 *Mov
 	}
 }
 
-// nextMove return the nextMove move of the position
+// nextMove returns the next move of the position, skipping the excluded moves
 func (mg *MoveGenerator) nextMove() (move Move) {
+	for move = mg.nextStagedMove(); move != NoMove && mg.isExcluded(move); move = mg.nextStagedMove() {
+		mg.moveNumber-- // Excluded moves do not count as selected
+	}
+	return
+}
+
+// isExcluded returns if the move passed should not be returned by the generator
+func (mg *MoveGenerator) isExcluded(move Move) bool {
+	if move == mg.excludedMove {
+		return true
+	}
+	if mg.allowed != nil && mg.allowed.length > 0 && !mg.allowed.contains(move) {
+		return true
+	}
+	return mg.excluded != nil && mg.excluded.contains(move)
+}
+
+// nextStagedMove return the next move of the current stage of the generator
+func (mg *MoveGenerator) nextStagedMove() (move Move) {
 	mg.moveNumber++
 	switch mg.stage {
 	case HashMoveStage:
@@ -100,7 +123,7 @@ func (mg *MoveGenerator) nextMove() (move Move) {
 		mg.stage = QuietStage
 		if !mg.skipQuiets {
 			mg.pos.generateQuiets(mg.moves, &mg.pd)
-			mg.moves.scoreQuiets(mg.quietHistory, mg.pos.Turn, mg.badCapLength)
+			mg.moves.scoreQuiets(mg.quietHistory, &mg.continuations, mg.pos, mg.badCapLength)
 		}
 		fallthrough
 	case QuietStage:
@@ -546,27 +569,24 @@ func (mg *MoveGenerator) isLegal(move Move) bool {
 			return false
 		}
 
-		capturedPawnBB := pawnPushesTable[side][from]
+		capturedPawnBB := pawnPushesTable[side.Opponent()][Bsf(mg.pos.enPassantTarget)]
 
 		if fromBB&mg.pd.pinnedPieces > 0 {
 			return false
 		}
 
-		if mg.pos.enPassantTarget == toBB && potentialEpCapturers(mg.pos, side)&fromBB > 0 {
-
-			if mg.pd.checkRestrictedSquares != AllSquares {
-				if (mg.pos.enPassantTarget&mg.pd.checkRestrictedSquares) == 0 &&
-					(capturedPawnBB&mg.pd.checkRestrictedSquares) == 0 {
-					return false
-				}
-			}
+		if mg.pos.enPassantTarget != toBB || potentialEpCapturers(mg.pos, side)&fromBB == 0 {
+			return false
+		}
 
-			if isEnPassantHorizontalPinned(mg.pos, fromBB, capturedPawnBB, side, &mg.pd) {
+		if mg.pd.checkRestrictedSquares != AllSquares {
+			if (mg.pos.enPassantTarget&mg.pd.checkRestrictedSquares) == 0 &&
+				(capturedPawnBB&mg.pd.checkRestrictedSquares) == 0 {
 				return false
 			}
 		}
 
-		return true
+		return !isEnPassantHorizontalPinned(mg.pos, fromBB, capturedPawnBB, side, &mg.pd)
 	}
 
 	// Promotions
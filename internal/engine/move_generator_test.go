@@ -91,6 +91,63 @@ func TestMoveGeneratorGetsAllMoves(t *testing.T) {
 	}
 }
 
+func TestMoveGeneratorSkipsExcludedMoves(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString("1b4k1/5pp1/3r3p/4P3/5PN1/3RK3/8/8 w - - 0 1") // 3 captures + 17 non capt
+	hashMove := NoMove
+	killers := Killer{NoMove, NoMove}
+	cm := NoMove
+
+	ml := NewMoveList()
+	pd := pos.generatePositionData()
+	pos.generateNoisy(ml, &pd)
+	excluded := movesSearched{}
+	excluded.add(ml.moves[0])
+	excluded.add(ml.moves[1])
+
+	mg := NewMoveGenerator(pos, &hashMove, &killers[0], &killers[1], &cm, &QuietHistoryTable{}, &NoisyHistoryTable{}, false)
+	mg.excluded = &excluded
+	for move := mg.nextMove(); move != NoMove; move = mg.nextMove() {
+		if excluded.contains(move) {
+			t.Errorf("Expected move %v to be excluded", move)
+		}
+	}
+
+	expected := 18
+	got := mg.moveNumber
+
+	if got != expected {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
+
+func TestMoveGeneratorOnlyReturnsAllowedMoves(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString("1b4k1/5pp1/3r3p/4P3/5PN1/3RK3/8/8 w - - 0 1")
+	hashMove := NoMove
+	killers := Killer{NoMove, NoMove}
+	cm := NoMove
+
+	ml := NewMoveList()
+	pd := pos.generatePositionData()
+	pos.generateQuiets(ml, &pd)
+	allowed := movesSearched{}
+	allowed.add(ml.moves[2])
+
+	mg := NewMoveGenerator(pos, &hashMove, &killers[0], &killers[1], &cm, &QuietHistoryTable{}, &NoisyHistoryTable{}, false)
+	mg.allowed = &allowed
+
+	expected := ml.moves[2]
+	got := mg.nextMove()
+
+	if got != expected {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+	if next := mg.nextMove(); next != NoMove {
+		t.Errorf("Expected: %v, got: %v", NoMove, next)
+	}
+}
+
 func TestStraightPinnedPieces(t *testing.T) {
 	pos := NewPosition()
 	pos.AddPiece(WhiteKing, e1)
@@ -978,3 +1035,43 @@ func TestIsEnPassnatHorizontalPinned(t *testing.T) {
 		t.Errorf("Expected: %v, got: %v", expected, got)
 	}
 }
+
+func TestMoveGeneratorSkipsSingularExcludedMove(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString("1b4k1/5pp1/3r3p/4P3/5PN1/3RK3/8/8 w - - 0 1") // 3 captures + 17 non capt
+	hashMove := *encodeMove(19, 43, capture)                             // Rxd6
+	killers := Killer{NoMove, NoMove}
+	cm := NoMove
+
+	mg := NewMoveGenerator(pos, &hashMove, &killers[0], &killers[1], &cm, &QuietHistoryTable{}, &NoisyHistoryTable{}, false)
+	mg.excludedMove = hashMove
+	for move := mg.nextMove(); move != NoMove; move = mg.nextMove() {
+		if move == hashMove {
+			t.Errorf("Expected move %v to be excluded", move)
+		}
+	}
+
+	expected := 19
+	got := mg.moveNumber
+
+	if got != expected {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
+
+func TestMoveGeneratorRejectsEnPassantKillerWhenItDoesNotResolveCheck(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString("rnb1k2r/pp3ppp/2p5/2bpP3/2q5/6PK/PPPNN2P/R1BQ3R w kq d6 0 11") // d7d5 discovers a check from c8
+	hashMove := NoMove
+	killers := Killer{*encodeMove(36, 43, epCapture), NoMove}
+	cm := NoMove
+
+	mg := NewMoveGenerator(pos, &hashMove, &killers[0], &killers[1], &cm, &QuietHistoryTable{}, &NoisyHistoryTable{}, false)
+
+	expected := false
+	got := mg.isLegal(killers[0])
+
+	if got != expected {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
@@ -39,10 +39,11 @@ func (ml *MoveList) scoreNoisy(pos *Position, nh *NoisyHistoryTable) {
 	}
 }
 
-// scoreQuiets scores the non captures moves by history score
-func (ml *MoveList) scoreQuiets(qh *QuietHistoryTable, side Color, startIndex int) {
+// scoreQuiets scores the non captures moves by history and continuation histories score
+func (ml *MoveList) scoreQuiets(qh *QuietHistoryTable, ch *continuations, pos *Position, startIndex int) {
 	for i := startIndex; i < ml.length; i++ {
-		ml.scores[i] = qh[side][ml.moves[i].from()][ml.moves[i].to()]
+		from, to := ml.moves[i].from(), ml.moves[i].to()
+		ml.scores[i] = qh[pos.Turn][from][to] + ch.score(pos.PieceAt(from), to)
 	}
 }
 
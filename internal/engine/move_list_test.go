@@ -35,13 +35,35 @@ func TestListOfMovesScoreNonCaptures(t *testing.T) {
 	hm[White][18][26] = 10
 	hm[White][42][50] = 100 // Pawn advance to 7 rank is historically better
 
-	lm.scoreQuiets(&hm, White, 1) // starts from non capture moves
+	lm.scoreQuiets(&hm, &continuations{}, pos, 1) // starts from non capture moves
 
 	if lm.scores[1] != 10 || lm.scores[2] != 100 {
 		t.Errorf("Expected: %v, got: %v", []int{0, 10, 100}, lm.scores)
 	}
 }
 
+func TestListOfMovesScoresQuietsWithContinuationHistory(t *testing.T) {
+	lm := NewMoveList()
+	pos := NewPosition()
+	pos.LoadFromFenString("8/6k1/2P2pp1/5P2/5K2/2P5/8/8 w - - 0 1")
+	m1 := encodeMove(18, 26, quiet) // Pawn advance to 4 rank
+	m2 := encodeMove(42, 50, quiet) // Pawn advance to 7 rank
+
+	lm.add(*m1)
+	lm.add(*m2)
+
+	hm := QuietHistoryTable{}
+	hm[White][42][50] = 100
+	followUp := ContinuationHistory{}
+	followUp[WhitePawn][26] = 300 // Pawn advance to 4 rank is better after the previous move
+
+	lm.scoreQuiets(&hm, &continuations{&followUp, nil}, pos, 0)
+
+	if lm.scores[0] != 300 || lm.scores[1] != 100 {
+		t.Errorf("Expected: %v, got: %v", []int{300, 100}, lm.scores[:2])
+	}
+}
+
 func TestListOfMovesGetBestMoveIndex(t *testing.T) {
 	pos := NewPosition()
 	pos.LoadFromFenString("4k3/8/3p1q2/4P3/4K3/8/8/8 w - - 0 1")
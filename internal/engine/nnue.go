@@ -0,0 +1,243 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"sync"
+)
+
+// Constants of the network architecture and its quantization
+const (
+	NNUEInputSize  = 768 // One input for each piece (relative to the side of the perspective) on each square
+	NNUEHiddenSize = 128 // Neurons of the hidden layer of each perspective
+	NNUEQA         = 255 // Quantization of the hidden layer. Activations are clipped between 0 and QA
+	NNUEQB         = 64  // Quantization of the output weights
+	NNUEScale      = 400 // Scale of the output of the network to centipawns
+)
+
+// nnueMagic identifies the network files
+var nnueMagic = [4]byte{'A', 'C', 'N', 'N'}
+
+// defaultNetworkFile is the network embedded in the binary. It's not trained, only bootstrapped from the piece
+// square tables, so it's a placeholder to exercise the NNUE code and the evaluation is disabled by default
+//
+//go:embed nets/default.nnue
+var defaultNetworkFile []byte
+
+// Network is a 768->128x2->1 perspective network. Each side has an accumulator with the hidden layer computed
+// from its perspective, and the output layer weights the accumulator of the side to move first
+type Network struct {
+	FeatureWeights [NNUEInputSize][NNUEHiddenSize]int16
+	FeatureBias    [NNUEHiddenSize]int16
+	OutputWeights  [2 * NNUEHiddenSize]int16
+	OutputBias     int32
+}
+
+// DefaultNetwork returns the network embedded in the binary
+var DefaultNetwork = sync.OnceValue(func() *Network {
+	network, err := ReadNetwork(bytes.NewReader(defaultNetworkFile))
+	if err != nil {
+		panic("invalid embedded network: " + err.Error())
+	}
+	return network
+})
+
+// BootstrapNetwork returns a network that evaluates the pieces by the piece square tables, at the middle of the
+// game phase. Each neuron adds the pieces of a type and color on a file, and the output weights scale it back to
+// centipawns, so the network starts from a known evaluation before being trained
+func BootstrapNetwork() *Network {
+	maxPieces := [6]float64{1, 1, 2, 2, 2, 2} // Pieces of each type on a file before a neuron saturates
+	network := &Network{}
+
+	for color := range 2 {
+		sign := float64(1 - 2*color)
+		for role := range 6 {
+			piece := pieceColor(role, Color(color))
+			value := func(sq int) float64 {
+				score := float64(middlegamePiecesScore[piece][sq]+endgamePiecesScore[piece][sq]) / 2
+				if role == King {
+					score -= float64(MiddlegamePieceValue[King]+EndgamePieceValue[King]) / 2
+				}
+				return score
+			}
+
+			for file := range 8 {
+				neuron := (color*6+role)*8 + file
+				maxValue := 0.0
+				for rank := range 8 {
+					maxValue = max(maxValue, math.Abs(value(rank*8+file)))
+				}
+				if maxValue == 0 {
+					continue
+				}
+
+				// The activation of the neuron moves from the middle of its range by the score of the pieces
+				scale := 0.5 * NNUEScale / (maxPieces[role] * maxValue)
+				network.FeatureBias[neuron] = int16(math.Round(0.5 * NNUEQA))
+				for rank := range 8 {
+					sq := rank*8 + file
+					feature := (color*6+role)*64 + sq
+					network.FeatureWeights[feature][neuron] = int16(math.Round(scale * value(sq) / NNUEScale * NNUEQA))
+				}
+
+				// Both perspectives share the output, so the weights of each one are halved
+				weight := int16(math.Round(sign / scale / 2 * NNUEQB))
+				network.OutputWeights[neuron] = weight
+				network.OutputWeights[NNUEHiddenSize+neuron] = -weight
+			}
+		}
+	}
+	return network
+}
+
+// ReadNetwork reads a network in the format written by Write
+func ReadNetwork(r io.Reader) (*Network, error) {
+	var magic [4]byte
+	var hiddenSize uint32
+	if err := binary.Read(r, binary.LittleEndian, &magic); err != nil || magic != nnueMagic {
+		return nil, errors.New("not a network file")
+	}
+	if err := binary.Read(r, binary.LittleEndian, &hiddenSize); err != nil || hiddenSize != NNUEHiddenSize {
+		return nil, errors.New("network hidden layer size not supported")
+	}
+
+	network := &Network{}
+	if err := binary.Read(r, binary.LittleEndian, network); err != nil {
+		return nil, errors.New("network file truncated")
+	}
+	return network, nil
+}
+
+// LoadNetwork reads the network of the file passed
+func LoadNetwork(path string) (*Network, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadNetwork(bufio.NewReader(file))
+}
+
+// Write writes the network: the magic bytes, the hidden layer size and the weights in little endian
+func (n *Network) Write(w io.Writer) error {
+	if err := binary.Write(w, binary.LittleEndian, nnueMagic); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint32(NNUEHiddenSize)); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.LittleEndian, n)
+}
+
+// Accumulator contains the hidden layer of the network from the perspective of each side. It's updated
+// incrementally when the pieces are added or removed from the position
+type Accumulator struct {
+	network *Network // Network of the values. Nil when the accumulator is not used
+	values  [2][NNUEHiddenSize]int16
+}
+
+// NNUEFeature returns the input of the network of the piece on the square, from the perspective passed. Black
+// sees the board mirrored vertically, with the colors of the pieces swapped
+func NNUEFeature(piece int, square int, perspective Color) int {
+	color := piece / 6
+	if perspective == Black {
+		square ^= 56
+		color ^= 1
+	}
+	return (color*6+pieceRole(piece))*64 + square
+}
+
+// refresh computes the accumulator of the position from scratch with the network passed
+func (acc *Accumulator) refresh(pos *Position, network *Network) {
+	acc.network = network
+	acc.values = [2][NNUEHiddenSize]int16{network.FeatureBias, network.FeatureBias}
+
+	for piece, bb := range pos.Pieces {
+		for bb > 0 {
+			acc.add(piece, Bsf(bb.NextBit()))
+		}
+	}
+}
+
+// add adds the piece on the square to the accumulator
+func (acc *Accumulator) add(piece int, square int) {
+	for _, perspective := range []Color{White, Black} {
+		weights := &acc.network.FeatureWeights[NNUEFeature(piece, square, perspective)]
+		values := &acc.values[perspective]
+		for i := range values {
+			values[i] += weights[i]
+		}
+	}
+}
+
+// remove removes the piece on the square from the accumulator
+func (acc *Accumulator) remove(piece int, square int) {
+	for _, perspective := range []Color{White, Black} {
+		weights := &acc.network.FeatureWeights[NNUEFeature(piece, square, perspective)]
+		values := &acc.values[perspective]
+		for i := range values {
+			values[i] -= weights[i]
+		}
+	}
+}
+
+// evaluate returns the output of the network for the accumulator, relative to the side to move
+func (n *Network) evaluate(acc *Accumulator, side Color) int {
+	output := 0
+	us, them := &acc.values[side], &acc.values[side.Opponent()]
+	for i := range NNUEHiddenSize {
+		output += int(clippedReLU(us[i])) * int(n.OutputWeights[i])
+		output += int(clippedReLU(them[i])) * int(n.OutputWeights[NNUEHiddenSize+i])
+	}
+	return (output + int(n.OutputBias)) * NNUEScale / (NNUEQA * NNUEQB)
+}
+
+// clippedReLU returns the activation of the hidden layer neuron
+func clippedReLU(value int16) int16 {
+	return max(0, min(NNUEQA, value))
+}
+
+// evaluateNNUE returns the score of the position by the network, relative to the side to move. The accumulator
+// of the position is computed from scratch when it wasn't computed by the network of the evaluation
+func (ev *Evaluation) evaluateNNUE(pos *Position) int {
+	if pos.accumulator.network != ev.Network {
+		pos.accumulator.refresh(pos, ev.Network)
+	}
+	return ev.Network.evaluate(&pos.accumulator, pos.Turn)
+}
+
+// SetEvalFile loads the network of the file passed, used when the NNUE evaluation is enabled. An empty path
+// loads the embedded network
+func (s *Search) SetEvalFile(path string) error {
+	network := DefaultNetwork()
+	if path != "" && path != "<empty>" {
+		var err error
+		if network, err = LoadNetwork(path); err != nil {
+			return err
+		}
+	}
+
+	s.network = network
+	if s.Evaluation.Network != nil {
+		s.Evaluation.Network = network
+	}
+	return nil
+}
+
+// SetUseNNUE switches the evaluation between the network and the hand crafted evaluation
+func (s *Search) SetUseNNUE(use bool) {
+	s.Evaluation.Network = nil
+	if use {
+		if s.network == nil {
+			s.network = DefaultNetwork()
+		}
+		s.Evaluation.Network = s.network
+	}
+}
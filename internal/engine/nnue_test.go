@@ -0,0 +1,138 @@
+package engine
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"testing"
+)
+
+var updateNetwork = flag.Bool("update", false, "generate the embedded network from the piece square tables")
+
+func TestBootstrapNetwork(t *testing.T) {
+	if !*updateNetwork {
+		t.Skip("Run with -update to generate the embedded network")
+	}
+
+	file, err := os.Create("nets/default.nnue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if err := BootstrapNetwork().Write(file); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadNetwork(t *testing.T) {
+	network := BootstrapNetwork()
+	buf := bytes.Buffer{}
+	network.Write(&buf)
+
+	got, err := ReadNetwork(&buf)
+
+	if err != nil || *got != *network {
+		t.Errorf("Expected: %v, got: %v", "the same network", err)
+	}
+}
+
+func TestReadNetworkWithAnotherHiddenSize(t *testing.T) {
+	data := append([]byte("ACNN"), 0, 1, 0, 0)
+
+	_, err := ReadNetwork(bytes.NewReader(data))
+
+	if err == nil {
+		t.Errorf("Expected: %v, got: %v", "an error", err)
+	}
+}
+
+func TestAccumulatorIsUpdatedIncrementally(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString("r3k2r/pPppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	network := DefaultNetwork()
+	pos.accumulator.refresh(pos, network)
+
+	// Make and unmake all the moves up to depth 2, including castles, promotions and captures
+	var check func(depth int)
+	check = func(depth int) {
+		expected := Accumulator{}
+		expected.refresh(pos, network)
+		if pos.accumulator != expected {
+			t.Fatalf("Expected: %v, got: %v at %v", "the refreshed accumulator", "a different one", pos.ToFen())
+		}
+		if depth == 0 {
+			return
+		}
+
+		ml := legalMoves(pos)
+		for _, move := range ml.moves[:ml.length] {
+			pos.MakeMove(&move)
+			check(depth - 1)
+			pos.UnmakeMove(&move)
+		}
+	}
+	check(2)
+}
+
+func TestEvaluateNNUE(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fen      string
+		expected func(score int) bool
+	}{
+		{"starting position", StartingFenString, func(score int) bool { return score == 0 }},
+		{"a queen up", "rnb1kbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 1", func(score int) bool { return score > 800 }},
+		{"a queen up, black to move", "rnb1kbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1", func(score int) bool { return score < -800 }},
+	}
+
+	ev := NewEvaluation(DefaultPawnHashTableSizeInMb)
+	ev.Network = DefaultNetwork()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pos := NewPosition()
+			pos.LoadFromFenString(tc.fen)
+
+			got := ev.Evaluate(pos)
+
+			if !tc.expected(got) {
+				t.Errorf("Expected: %v, got: %v", tc.name, got)
+			}
+		})
+	}
+}
+
+func TestSetUseNNUE(t *testing.T) {
+	s := NewSearch()
+
+	s.SetUseNNUE(true)
+	if s.Evaluation.Network != DefaultNetwork() {
+		t.Errorf("Expected: %v, got: %v", "the default network", s.Evaluation.Network)
+	}
+
+	s.SetUseNNUE(false)
+	if s.Evaluation.Network != nil {
+		t.Errorf("Expected: %v, got: %v", nil, s.Evaluation.Network)
+	}
+}
+
+func TestSetEvalFile(t *testing.T) {
+	s := NewSearch()
+	path := t.TempDir() + "/test.nnue"
+	network := BootstrapNetwork()
+	network.OutputBias = 1000
+	file, _ := os.Create(path)
+	network.Write(file)
+	file.Close()
+
+	s.SetUseNNUE(true)
+	err := s.SetEvalFile(path)
+
+	if err != nil || s.Evaluation.Network.OutputBias != network.OutputBias {
+		t.Errorf("Expected: %v, got: %v (%v)", network.OutputBias, s.Evaluation.Network.OutputBias, err)
+	}
+
+	if err := s.SetEvalFile(path + ".missing"); err == nil {
+		t.Errorf("Expected: %v, got: %v", "an error", err)
+	}
+}
@@ -82,6 +82,7 @@ type Position struct {
 	halfmoveClock   int
 	FullMoveNumber  int
 	positionHistory PositionHistory
+	accumulator     Accumulator // Hidden layer of the network, updated with the pieces when it's used
 }
 
 // PositionData contains relevant data for legal move validations of a position
@@ -144,6 +145,9 @@ func (pos *Position) AddPiece(piece int, square int) {
 	pos.Pieces[piece] |= bb
 	pos.Sides[int(piece/6)] |= bb
 	pos.Sides[All] |= bb
+	if pos.accumulator.network != nil {
+		pos.accumulator.add(piece, square)
+	}
 }
 
 // EmptySquares returns a Bitboard with the empty squares of the position
@@ -199,6 +203,14 @@ func (pos *Position) Check(side Color) bool {
 	return rookAttacks > 0
 }
 
+// givesCheck returns if the move passed gives check to the opponent
+func (pos *Position) givesCheck(move *Move) bool {
+	pos.MakeMove(move)
+	check := pos.Check(pos.Turn)
+	pos.UnmakeMove(move)
+	return check
+}
+
 // PinnedPieces returns a bitboard with the pieces pinned in the position for the side passed
 func (pos *Position) PinnedPieces(side Color) (pinned Bitboard) {
 	king := pos.KingPosition(side)
@@ -250,44 +262,48 @@ func (pos *Position) RemovePiece(piece int, square int) {
 	if pieceRole(piece) == Pawn {
 		pos.PawnHash = pos.PawnHash ^ zobristHashKeys.getPieceSquareKey(piece, square)
 	}
+	if pos.accumulator.network != nil {
+		pos.accumulator.remove(piece, square)
+	}
 }
 
-// isDraw returns if the current position is a draw by repetition, 50 move rule or insuficient material
-func (pos *Position) isDraw() bool {
-	// Instead of waiting to get a threefold repetition, Aconcagua assumes
-	// if a position is repeated once, it should be at least a draw. This helps
-	// avoid unecessary search and seems working well.
-	return pos.isThreefoldRepetition() ||
+// isDraw returns if the current position is a draw by repetition, 50 move rule or insuficient material.
+// Used by the search, where repetitions are checked with isRepetition
+func (pos *Position) isDraw(rootMoveCount int) bool {
+	return pos.isRepetition(rootMoveCount) ||
 		pos.halfmoveClock >= 100 ||
 		pos.insuficientMaterial()
 }
 
-// isThreefoldRepetition returns if the current position is a draw by threefold repetition
-func (pos *Position) isThreefoldRepetition() bool {
-	// If we have not enought moves so far, a repetition will never occur
-	if pos.positionHistory.moveCount <= 4 {
-		return false
-	}
-
-	// Calculate search limit based on halfmove clock
-	// A repetition cannot never occur after a halfmove clock reset
+// isRepetition returns if the current position is repeated after the root of the search, or if it's a threefold
+// repetition with the positions played before. The root is the number of moves played in the game before the search.
+// Instead of waiting to get a threefold repetition inside the search, Aconcagua assumes if a position is repeated
+// once, it should be at least a draw. This helps avoid unecessary search and seems working well.
+func (pos *Position) isRepetition(rootMoveCount int) bool {
 	lastIrreversibleMove := max(pos.positionHistory.moveCount-pos.halfmoveClock, 0)
 	reps := 0
 
-	// Check all positions back to the last irreversible move
-	// Only check positions with same side to move (every 2 plies)
 	for i := pos.positionHistory.moveCount - 2; i >= lastIrreversibleMove; i -= 2 {
-		if pos.positionHistory.previousPosition[i] == pos.Hash {
-			reps++
+		if pos.positionHistory.previousPosition[i] != pos.Hash {
+			continue
+		}
+		if i >= rootMoveCount {
+			return true
 		}
 
+		reps++
 		if reps >= 2 {
 			return true
 		}
 	}
 
 	return false
+}
 
+// isThreefoldRepetition returns if the current position has occurred three times in the game. Unlike the search
+// check, a single repetition of the position is not considered a draw
+func (pos *Position) isThreefoldRepetition() bool {
+	return pos.isRepetition(pos.positionHistory.moveCount)
 }
 
 // insuficientMaterial returns if the current position is a draw by insuficient material
@@ -301,10 +317,8 @@ func (pos *Position) insuficientMaterial() bool {
 	if pos.Pieces[WhiteRook] > 0 || pos.Pieces[BlackRook] > 0 {
 		return false
 	}
-	if pos.Pieces[WhiteBishop].count() > 1 || pos.Pieces[BlackBishop].count() > 1 {
-		return false
-	}
-	if pos.Pieces[WhiteKnight].count() > 1 || pos.Pieces[BlackKnight].count() > 1 {
+	// Two minor pieces of the same side, like a bishop and a knight, can mate
+	if (pos.Pieces[WhiteBishop]|pos.Pieces[WhiteKnight]).count() > 1 || (pos.Pieces[BlackBishop]|pos.Pieces[BlackKnight]).count() > 1 {
 		return false
 	}
 	if pos.Pieces[WhiteBishop].count() == pos.Pieces[BlackBishop].count() && pos.Pieces[WhiteBishop] > 0 {
@@ -643,6 +657,7 @@ func (pos *Position) LoadFromFenString(fen string) {
 
 	pos.Pieces = [12]Bitboard{}
 	pos.Sides = [3]Bitboard{}
+	pos.accumulator = Accumulator{}
 	pos.positionHistory.clear()
 	pos.positionHistory.previousPosition = [MaxHistoryMoves * 2]uint64{}
 
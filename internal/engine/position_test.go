@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"slices"
 	"strings"
 	"testing"
 )
@@ -459,6 +460,7 @@ func TestInsuficientMaterial(t *testing.T) {
 		{"2 knights and king vs 1 knight and king", "8/1kn5/8/8/3NN3/3K4/8/8 w - - 0 1", false},
 		{"knight and king vs king", "8/1k6/8/4N3/4K3/8/8/8 w - - 0 1", true},
 		{"bishop and king vs king", "8/1kb5/8/8/4K3/8/8/8 w - - 0 1", true},
+		{"bishop and knight and king vs king", "8/1k6/8/8/4K3/8/3BN3/8 w - - 0 1", false},
 	}
 
 	for _, tc := range testCases {
@@ -567,3 +569,16 @@ func TestThreefoldRepetitions(t *testing.T) {
 
 	}
 }
+
+func TestRepetitionCountsOnceAfterTheRoot(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString("5k2/6p1/pQ3p1p/1p6/4q3/2P3PK/1P5P/8 b - - 6 36")
+	pos.LoadMoves("e4f5", "h3g2", "f5e4", "g2h3")
+
+	expected := []bool{true, false, false}
+	got := []bool{pos.isRepetition(0), pos.isRepetition(pos.positionHistory.moveCount), pos.isThreefoldRepetition()}
+
+	if !slices.Equal(got, expected) {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
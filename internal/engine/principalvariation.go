@@ -3,21 +3,6 @@ package engine
 // pvLine is a principal variation line of a position
 type pvLine []Move
 
-// NewPvLine returns a new principal variation line with the capacity passed allocated
-func NewPvLine(capacity int) pvLine {
-	return make(pvLine, 0, capacity)
-}
-
-// insert inserts a move at the beginning of the pvLine
-func (pv *pvLine) insert(move Move, branchPv *pvLine) {
-	*pv = append([]Move{move}, *branchPv...)
-}
-
-// reset resets the pvLine
-func (pv *pvLine) reset() {
-	*pv = (*pv)[:0]
-}
-
 // String returns the string representation of the principal variation
 func (pv *pvLine) String() string {
 	moves := ""
@@ -27,3 +12,80 @@ func (pv *pvLine) String() string {
 	moves = moves[:len(moves)-1]
 	return moves
 }
+
+// PVTable is a triangular table with the principal variation found at each ply of the search.
+// The row of each ply holds the best move of the node followed by the principal variation of its child
+type PVTable struct {
+	moves  [MaxSearchDepth * 2][MaxSearchDepth * 2]Move
+	length [MaxSearchDepth * 2]int
+}
+
+// clear empties the principal variation of the ply passed
+func (pvt *PVTable) clear(ply int) {
+	pvt.length[ply] = ply
+}
+
+// update sets the move passed as the best move of the ply, followed by the principal variation of the next ply
+func (pvt *PVTable) update(ply int, move Move) {
+	pvt.moves[ply][ply] = move
+	next := ply + 1
+	if next >= len(pvt.length) {
+		pvt.length[ply] = next
+		return
+	}
+	length := max(pvt.length[next], next)
+	copy(pvt.moves[ply][next:length], pvt.moves[next][next:length])
+	pvt.length[ply] = length
+}
+
+// bestMove returns the first move of the principal variation of the ply passed
+func (pvt *PVTable) bestMove(ply int) Move {
+	if pvt.length[ply] <= ply {
+		return NoMove
+	}
+	return pvt.moves[ply][ply]
+}
+
+// line returns the principal variation found from the root, extended up to the depth passed with the hash moves
+// from the transposition table. Moves are played on the position to make sure that only legal moves are reported,
+// and the line is cut on the first move that leads to a repeated position
+func (s *Search) line(pos *Position, depth int) pvLine {
+	pv := pvLine{}
+	seen := map[uint64]bool{pos.Hash: true}
+
+	for i := 0; i < max(depth, s.pvTable.length[0]); i++ {
+		move := NoMove
+		if i < s.pvTable.length[0] {
+			move = s.pvTable.moves[0][i]
+		} else {
+			move = s.TranspositionTable.hashMove(pos.Hash)
+		}
+		if move == NoMove || !isLegalMove(pos, move) {
+			break
+		}
+
+		pos.MakeMove(&move)
+		repeated := seen[pos.Hash]
+		seen[pos.Hash] = true
+		pv = append(pv, move)
+		if repeated {
+			break
+		}
+	}
+
+	for i := len(pv) - 1; i >= 0; i-- {
+		pos.UnmakeMove(&pv[i])
+	}
+	return pv
+}
+
+// isLegalMove returns if the move passed is one of the legal moves of the position
+func isLegalMove(pos *Position, move Move) bool {
+	ml := legalMoves(pos)
+	for _, m := range ml.moves[:ml.length] {
+		if m == move {
+			return true
+		}
+	}
+	return false
+}
@@ -2,41 +2,112 @@ package engine
 
 import "testing"
 
-func TestResetPVLine(t *testing.T) {
-	pvLine := NewPvLine(100)
-	pvLine.insert(*encodeMove(0, 8, quiet), &pvLine)
-
-	pvLine.reset()
+func TestString(t *testing.T) {
+	pv := pvLine{*encodeMove(0, 8, quiet), *encodeMove(48, 40, quiet)}
 
-	expected := 0
-	got := len(pvLine)
+	expected := "a1a2 a7a6"
+	got := pv.String()
 
 	if got != expected {
 		t.Errorf("Expected: %v, got: %v", expected, got)
 	}
 }
 
-func TestInsert(t *testing.T) {
-	pvLine := NewPvLine(100)
-	move := encodeMove(0, 8, quiet)
-	pvLine.insert(*move, &pvLine)
+func TestPVTableUpdateCopiesTheChildLine(t *testing.T) {
+	pvt := PVTable{}
+	first, second, third := *encodeMove(12, 28, doublePawnPush), *encodeMove(52, 36, doublePawnPush), *encodeMove(6, 21, quiet)
 
-	expected := *move
-	got := pvLine[0]
+	pvt.clear(2)
+	pvt.update(2, third)
+	pvt.clear(1)
+	pvt.update(1, second)
+	pvt.clear(0)
+	pvt.update(0, first)
 
-	if got != expected {
+	expected := []Move{first, second, third}
+	got := pvt.moves[0][:pvt.length[0]]
+
+	if len(got) != len(expected) || got[0] != expected[0] || got[1] != expected[1] || got[2] != expected[2] {
 		t.Errorf("Expected: %v, got: %v", expected, got)
 	}
 }
 
-func TestString(t *testing.T) {
-	pvLine := NewPvLine(100)
-	pvLine.insert(*encodeMove(0, 8, quiet), &pvLine)
+func TestPVTableClearDiscardsTheChildLine(t *testing.T) {
+	pvt := PVTable{}
+	pvt.clear(1)
+	pvt.update(1, *encodeMove(52, 36, doublePawnPush))
+
+	pvt.clear(1)
+	pvt.clear(0)
+	pvt.update(0, *encodeMove(12, 28, doublePawnPush))
 
-	expected := "a1a2"
-	got := pvLine.String()
+	expected := 1
+	got := pvt.length[0]
 
 	if got != expected {
 		t.Errorf("Expected: %v, got: %v", expected, got)
 	}
 }
+
+func TestLineIsExtendedWithHashMoves(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString(StartingFenString)
+	s := NewSearch()
+	e4, e5 := *encodeMove(12, 28, doublePawnPush), *encodeMove(52, 36, doublePawnPush)
+
+	s.pvTable.clear(0)
+	s.pvTable.update(0, e4)
+	pos.MakeMove(&e4)
+	s.TranspositionTable.store(&s.ttStats, pos.Hash, 1, 0, FlagExact, 0, 0, e5)
+	pos.UnmakeMove(&e4)
+
+	expected := "e2e4 e7e5"
+	got := s.line(pos, 5)
+
+	if got.String() != expected {
+		t.Errorf("Expected: %v, got: %v", expected, got.String())
+	}
+}
+
+func TestLineSkipsIllegalHashMoves(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString(StartingFenString)
+	s := NewSearch()
+	e4 := *encodeMove(12, 28, doublePawnPush)
+
+	s.pvTable.clear(0)
+	s.pvTable.update(0, e4)
+	pos.MakeMove(&e4)
+	s.TranspositionTable.store(&s.ttStats, pos.Hash, 1, 0, FlagExact, 0, 0, e4) // e2e4 is not legal for black
+	pos.UnmakeMove(&e4)
+
+	expected := "e2e4"
+	got := s.line(pos, 5)
+
+	if got.String() != expected {
+		t.Errorf("Expected: %v, got: %v", expected, got.String())
+	}
+}
+
+func TestLineStopsOnRepeatedPositions(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString(StartingFenString)
+	s := NewSearch()
+	moves := []Move{*encodeMove(6, 21, quiet), *encodeMove(62, 45, quiet), *encodeMove(21, 6, quiet), *encodeMove(45, 62, quiet)}
+
+	for _, move := range moves {
+		s.TranspositionTable.store(&s.ttStats, pos.Hash, 1, 0, FlagExact, 0, 0, move)
+		pos.MakeMove(&move)
+	}
+	for i := len(moves) - 1; i >= 0; i-- {
+		pos.UnmakeMove(&moves[i])
+	}
+	s.pvTable.clear(0)
+
+	expected := "g1f3 g8f6 f3g1 f6g8"
+	got := s.line(pos, 20)
+
+	if got.String() != expected {
+		t.Errorf("Expected: %v, got: %v", expected, got.String())
+	}
+}
@@ -3,55 +3,74 @@ package engine
 // SEEPieceValues contains the value of the pieces for static exchange evaluation
 var SEEPieceValues = [6]int{10000, 900, 500, 300, 300, 100}
 
-// Quiescent is an evaluation function that takes into account some dynamic possibilities
-func Quiescent(pos *Position, s *Search, alpha int, beta int, ply int) int {
-	s.nodes++
-	if s.TimeControl.stop {
+// Quiescent is an evaluation function that takes into account some dynamic possibilities.
+// Depth is 0 on the first ply of the quiescence search and it's decreased on each ply
+func Quiescent(pos *Position, s *Search, alpha int, beta int, ply int, depth int) int {
+	s.nodes.Add(1)
+	if s.TimeControl.stop.Load() || s.TimeControl.reachedNodesLimit(s.limitedNodes()) {
 		return 0
 	}
 
 	s.seldepth = max(s.seldepth, uint8(ply))
 	// If the position is a draw avoid redundant search
-	if pos.isDraw() {
+	if pos.isDraw(s.rootMoveCount) {
 		return 0
 	}
 
 	// Transposition Table probe
-	ttScore, ttEval, ttMove, ttHit := s.TranspositionTable.probe(pos.Hash, 0, ply, alpha, beta)
+	ttScore, ttEval, ttMove, ttHit, _ := s.TranspositionTable.probe(&s.ttStats, pos.Hash, 0, ply, alpha, beta)
 	if ttHit {
 		return ttScore
 	}
 
-	staticEval := s.evaluate(pos, ttMove, ttEval)
-
-	if staticEval >= beta {
-		return beta
+	rawEval := s.evaluate(pos, ttMove, ttEval)
+	staticEval := s.correctEval(pos, rawEval)
+	if ply >= MaxSearchDepth {
+		return staticEval
 	}
 
-	if staticEval > alpha {
-		alpha = staticEval
+	// When in check we can't stand pat, all evasions are searched to detect mates
+	isCheck := pos.Check(pos.Turn)
+	if !isCheck {
+		if staticEval >= beta {
+			return beta
+		}
+
+		if staticEval > alpha {
+			alpha = staticEval
+		}
 	}
 
+	quietChecks := s.QuiescentChecks && depth == 0 && !isCheck
 	noMove := NoMove
-	mg := NewMoveGenerator(pos, &ttMove, &noMove, &noMove, &noMove, &s.quietHistory, &s.noisyHistory, true)
+	mg := NewMoveGenerator(pos, &ttMove, &noMove, &noMove, &noMove, &s.quietHistory, &s.noisyHistory, !isCheck && !quietChecks)
 
 	flag := FlagAlpha
 	newScore := MinInt
 	bestMove := NoMove
+	legalMoves := 0
 
 	for move := mg.nextMove(); move != NoMove; move = mg.nextMove() {
-
-		// See Pruning. Use already computed see value in move generator
-		if mg.moves.scores[mg.moves.length] < 0 {
-			continue
+		legalMoves++
+
+		if !isCheck {
+			if mg.stage == QuietStage {
+				// Only quiet moves that give check without losing material are searched
+				if pos.see(&move) < 0 || !pos.givesCheck(&move) {
+					continue
+				}
+			} else if mg.moves.scores[mg.moves.length] < 0 {
+				// See Pruning. Use already computed see value in move generator
+				continue
+			}
 		}
 
 		pos.MakeMove(&move)
-		newScore = -Quiescent(pos, s, -beta, -alpha, ply+1)
+		newScore = -Quiescent(pos, s, -beta, -alpha, ply+1, depth-1)
 		pos.UnmakeMove(&move)
 
 		if newScore >= beta {
-			s.TranspositionTable.store(pos.Hash, 0, ply, FlagBeta, beta, staticEval, move)
+			s.TranspositionTable.store(&s.ttStats, pos.Hash, 0, ply, FlagBeta, beta, rawEval, move)
 			return beta
 		}
 		if newScore > alpha {
@@ -61,7 +80,14 @@ func Quiescent(pos *Position, s *Search, alpha int, beta int, ply int) int {
 		}
 	}
 
-	s.TranspositionTable.store(pos.Hash, 0, ply, flag, alpha, staticEval, bestMove)
+	// Checkmated, no evasions found
+	if isCheck && legalMoves == 0 {
+		score := -MateScore + ply
+		s.TranspositionTable.store(&s.ttStats, pos.Hash, 0, ply, FlagExact, score, rawEval, NoMove)
+		return score
+	}
+
+	s.TranspositionTable.store(&s.ttStats, pos.Hash, 0, ply, flag, alpha, rawEval, bestMove)
 	return alpha
 }
 
@@ -112,3 +112,37 @@ func TestSeePushPromotions(t *testing.T) {
 		t.Errorf("Expected: %v, got: %v", expected, got)
 	}
 }
+
+func TestQuiescentDetectsCheckmate(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString("R5k1/5ppp/8/8/8/8/8/6K1 b - - 0 1") // Black is checkmated
+	s := NewSearch()
+	s.TimeControl.Initialize(DepthStrategy, int(pos.Turn), pos.FullMoveNumber, Clock{})
+
+	expected := -MateScore + 1
+	got := Quiescent(pos, s, -MateScore, MateScore, 1, -1)
+
+	if got != expected {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
+
+func TestQuiescentSearchesQuietChecksOnFirstPly(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString("6k1/5ppp/8/8/8/8/5PPP/3R2K1 w - - 0 1") // Rd8# is a quiet check
+	s := NewSearch()
+	s.QuiescentChecks = true
+	s.TimeControl.Initialize(DepthStrategy, int(pos.Turn), pos.FullMoveNumber, Clock{})
+
+	expected := MateScore - 2
+	got := Quiescent(pos, s, -MateScore, MateScore, 1, 0)
+
+	if got != expected {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+
+	s.TranspositionTable.Clear()
+	if got := Quiescent(pos, s, -MateScore, MateScore, 1, -1); got >= MateScore-MaxSearchDepth {
+		t.Errorf("Expected: %v, got: %v", "no mate found after the first ply", got)
+	}
+}
@@ -3,8 +3,14 @@ package engine
 import (
 	"fmt"
 	"math"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/gabtar/aconcagua/internal/syzygy"
 )
 
 // Constants to use in the search
@@ -20,6 +26,18 @@ const (
 	SEEPruningDepth               = 8
 	SEECaptureMargin              = 120
 	SEEQuietMargin                = 80
+	InfoReportInterval            = 1000 // Min time in ms between progress reports to the GUI
+	LMRHistoryDivisor             = 8192 // History score needed to reduce one ply less (or more) on quiet moves
+	LMRCaptureHistoryDivisor      = 4096 // Capture history score needed to reduce one ply less (or more) on captures
+	SEECaptureHistoryDivisor      = 64   // Capture history needed to allow one more centipawn lost on SEE pruning
+	CaptureHistoryBonusScale      = 8    // Scales the capture history bonus, to make it relevant compared to the victim value on move ordering
+	SingularExtensionDepth        = 8
+	ProbCutDepth                  = 5
+	RazoringDepth                 = 3
+	RazoringMargin                = 250
+	ProbCutMargin                 = 100
+	ProbCutReduction              = 4
+	SingularMargin                = 3
 )
 
 var (
@@ -46,53 +64,89 @@ func init() {
 
 // Search is the main struct for the search
 type Search struct {
-	nodes              int
-	rootNodeCounts     [MaxLegalMoves]int
-	pvLine             pvLine
-	seldepth           uint8
-	killers            KillersTable
-	quietHistory       QuietHistoryTable
-	noisyHistory       NoisyHistoryTable
-	TranspositionTable TranspositionTable
-	counterMovesTable  CounterMoveTable
-	stack              Stack
-	TimeControl        TimeControl
-	Evaluation         Evaluation
+	nodes               atomic.Uint64 // Nodes searched since the start of the search
+	rootNodeCounts      [MaxLegalMoves]int
+	pvTable             PVTable
+	seldepth            uint8
+	killers             KillersTable
+	quietHistory        QuietHistoryTable
+	noisyHistory        NoisyHistoryTable
+	continuationHistory [2]ContinuationHistoryTable // Follow up histories of the moves 1 and 2 plies back
+	pawnCorrection      CorrectionHistoryTable      // Static eval corrections indexed by the pawn structure
+	materialCorrection  CorrectionHistoryTable      // Static eval corrections indexed by the non pawn material
+	TranspositionTable  *TranspositionTable
+	ttStats             TTStats // Usage stats of the transposition table by this thread since the start of the search
+	counterMovesTable   CounterMoveTable
+	stack               Stack
+	TimeControl         *TimeControl
+	Evaluation          Evaluation
+	network             *Network           // Network loaded with EvalFile. Nil until the NNUE evaluation is used
+	MultiPV             int                // Number of principal variations to search and report
+	Strength            Strength           // Limits the playing strength (UCI_LimitStrength)
+	ShowWDL             bool               // Appends the win/draw/loss probabilities to the info output
+	QuiescentChecks     bool               // Generates the quiet checks at the first ply of the quiescence search
+	Debug               bool               // Sends diagnostics of the search to the GUI (uci debug)
+	Tablebases          *syzygy.Tablebases // Syzygy tablebases (SyzygyPath). Nil when not set
+	SyzygyProbeDepth    int                // Min depth to probe the tablebases inside the search
+	tbCardinality       int                // Max pieces to probe the tablebases inside the search. 0 when the root is in the tables
+	tbHits              atomic.Uint64      // Positions found in the tablebases since the start of the search
+	tbMoves             tbMoveLists        // Moves of each capture searched when probing the tablebases
+	rootExcluded        movesSearched      // Root moves excluded from the search, already reported in multipv mode
+	searchMoves         movesSearched      // Root moves the search is restricted to (uci searchmoves). Empty means all moves
+	stdout              chan string        // Channel to report the search progress. Only set on the main thread
+	lastReportTime      time.Time          // Time of the last progress report
+	pvIdx               int                // Current root move slot searched in multipv mode
+	rootMoveCount       int                // Moves played in the game before the root, to detect repetitions inside the search
+	searching           atomic.Bool        // Set until the best move of the search is sent
+	helpers             []*Search          // Lazy SMP helper threads
+	helpersGroup        sync.WaitGroup     // Keeps track of the helper threads running
+	mainThread          *Search            // The main search thread of a helper, nil on the main thread
 }
 
 // NewSearch returns a pointer to a new Search struct
 func NewSearch() *Search {
+	return newSearchWithTable(NewTranspositionTable(DefaultTableSizeInMb))
+}
+
+// newSearchWithTable returns a pointer to a new Search struct that uses the transposition table passed
+func newSearchWithTable(tt *TranspositionTable) *Search {
 	return &Search{
-		nodes:              0,
-		pvLine:             NewPvLine(MaxSearchDepth),
 		killers:            KillersTable{},
 		quietHistory:       QuietHistoryTable{},
 		noisyHistory:       NoisyHistoryTable{},
-		TranspositionTable: *NewTranspositionTable(DefaultTableSizeInMb),
+		TranspositionTable: tt,
 		counterMovesTable:  CounterMoveTable{},
 		stack:              Stack{},
-		TimeControl:        *NewTimeControl(),
-		Evaluation:         *NewEvaluation(DefaultPawnHashTableSizeInMb),
+		TimeControl:        NewTimeControl(),
+		Evaluation:         Evaluation{PawnCache: *NewPawnHashTable(DefaultPawnHashTableSizeInMb), Endgames: endgameTables},
+		MultiPV:            1,
+		Strength:           NewStrength(),
+		SyzygyProbeDepth:   DefaultSyzygyProbeDepth,
 	}
 }
 
 // clear clears the search
 func (s *Search) clear() {
-	s.nodes = 0
+	s.nodes.Store(0)
+	s.tbHits.Store(0)
+	s.ttStats = TTStats{}
 	s.killers.clear()
 	s.quietHistory.clear()
 	s.noisyHistory.clear()
+	s.continuationHistory[0].clear()
+	s.continuationHistory[1].clear()
+	s.pawnCorrection.clear()
+	s.materialCorrection.clear()
 	s.counterMovesTable.clear()
 	s.stack.clear()
-	s.TranspositionTable.newSearch()
 	s.Evaluation.PawnCache.newSearch()
 }
 
 // reset sets the new iteration parameters in the NewSearch
 func (s *Search) reset() {
 	s.seldepth = 0
-	s.nodes = 0
-	s.pvLine.reset()
+	s.pvTable.clear(0)
+	s.rootExcluded.length = 0
 	s.stack.clear()
 	s.TimeControl.iterationStartTime = time.Now()
 	for i := range s.rootNodeCounts {
@@ -102,7 +156,24 @@ func (s *Search) reset() {
 
 // Stop stops the search
 func (s *Search) Stop() {
-	s.TimeControl.stop = true
+	s.TimeControl.stop.Store(true)
+}
+
+// SetSearchMoves restricts the root moves of the next searches to the moves passed in uci notation.
+// Moves that are not legal in the position are ignored, if none of them is legal all moves are searched
+func (s *Search) SetSearchMoves(pos *Position, moves []string) {
+	s.searchMoves.length = 0
+	ml := legalMoves(pos)
+	for _, move := range ml.moves[:ml.length] {
+		if slices.Contains(moves, move.String()) {
+			s.searchMoves.add(move)
+		}
+	}
+}
+
+// PonderHit switches the current ponder search to the normal time limits
+func (s *Search) PonderHit() {
+	s.TimeControl.PonderHit()
 }
 
 // QuietHistoryTable is a table for holding the history of moves
@@ -139,15 +210,27 @@ func (qht *QuietHistoryTable) clear() {
 // NoisyHistoryTable is a table that stores score of previous captures searched indexed by attacker / victim type or promo type / to square
 type NoisyHistoryTable [6][10][64]int
 
+// noisyHistoryIndex returns the attacker and victim indexes of the move in the NoisyHistoryTable
+func noisyHistoryIndex(move *Move, pos *Position) (attacker int, victimIdx int) {
+	attacker = pieceRole(pos.PieceAt(move.from()))
+	victim := pos.getCapturedPiece(move)
+	victimIdx = pieceRole(victim)
+	if victim == NoPiece { // Simple Promotion Slot. NoPiece + (0-3) depending on move flag/promotion type
+		victimIdx = 6 + move.flag() - knightPromotion
+	}
+	return
+}
+
+// get returns the score of the move passed in the NoisyHistoryTable
+func (nht *NoisyHistoryTable) get(move *Move, pos *Position) int {
+	attacker, victimIdx := noisyHistoryIndex(move, pos)
+	return nht[attacker][victimIdx][move.to()]
+}
+
 // update updates the score of the move passed in the NoisyHistoryTable
 func (nht *NoisyHistoryTable) update(bonus int, move *Move, pos *Position) {
 	if move.flag() >= capture {
-		attacker := pieceRole(pos.PieceAt(move.from()))
-		victim := pos.getCapturedPiece(move)
-		victimIdx := pieceRole(victim)
-		if victim == NoPiece { // Simple Promotion Slot. NoPiece + (0-3) depending on move flag/promotion type
-			victimIdx = 6 + move.flag() - knightPromotion
-		}
+		attacker, victimIdx := noisyHistoryIndex(move, pos)
 
 		clampBonus := max(-MaxHistoryBonus, min(MaxHistoryBonus, bonus))
 		nht[attacker][victimIdx][move.to()] += clampBonus - nht[attacker][victimIdx][move.to()]*abs(clampBonus)/MaxHistoryBonus
@@ -158,7 +241,7 @@ func (nht *NoisyHistoryTable) update(bonus int, move *Move, pos *Position) {
 func (nht *NoisyHistoryTable) decrement(ms *movesSearched, cutoffMove *Move, depth int, pos *Position) {
 	for i := range ms.length {
 		if ms.moves[i] != *cutoffMove {
-			nht.update(-depth*depth, &ms.moves[i], pos)
+			nht.update(-depth*depth*CaptureHistoryBonusScale, &ms.moves[i], pos)
 		}
 	}
 }
@@ -174,6 +257,64 @@ func (nht *NoisyHistoryTable) clear() {
 	}
 }
 
+// ContinuationHistory holds the scores of the quiet moves, indexed by piece / to square, played after a previous move
+type ContinuationHistory [12][64]int
+
+// ContinuationHistoryTable is a table of continuation histories indexed by the piece / to square of a previous move
+type ContinuationHistoryTable [12][64]ContinuationHistory
+
+// clear clears all scores in the ContinuationHistoryTable
+func (cht *ContinuationHistoryTable) clear() {
+	*cht = ContinuationHistoryTable{}
+}
+
+// continuations contains the continuation histories of the moves played 1 and 2 plies back.
+// Entries are nil if there is no previous move (root or null move)
+type continuations [2]*ContinuationHistory
+
+// continuationHistories returns the continuation histories for the moves played at the given ply
+func (s *Search) continuationHistories(ply int) (ch continuations) {
+	for i := range ch {
+		if move, piece := s.stack.getPrior(ply, i+1); move != NoMove {
+			ch[i] = &s.continuationHistory[i][piece][move.to()]
+		}
+	}
+	return
+}
+
+// score returns the sum of the continuation histories scores of the piece moving to the square passed
+func (ch *continuations) score(piece int, to int) (score int) {
+	for _, h := range ch {
+		if h != nil {
+			score += h[piece][to]
+		}
+	}
+	return
+}
+
+// update increase the continuation histories scores of the quiet move using the gravity formula
+func (ch *continuations) update(bonus int, move *Move, pos *Position) {
+	if move.flag() >= capture {
+		return
+	}
+	piece := pos.PieceAt(move.from())
+	clampBonus := max(-MaxHistoryBonus, min(MaxHistoryBonus, bonus))
+	for _, h := range ch {
+		if h != nil {
+			h[piece][move.to()] += clampBonus - h[piece][move.to()]*abs(clampBonus)/MaxHistoryBonus
+		}
+	}
+}
+
+// decrement decrements the continuation histories scores of the quiet moves that did not produce the cutoff
+func (ch *continuations) decrement(ms *movesSearched, cutoffMove *Move, depth int, pos *Position) {
+	for i := range ms.length {
+		if ms.moves[i] != *cutoffMove {
+			ch.update(-depth*depth, &ms.moves[i], pos)
+		}
+	}
+}
+
 // movesSearched is an struct that holds the moves tried on an specific node of the search tree
 type movesSearched struct {
 	moves  [MaxLegalMoves]Move
@@ -186,6 +327,11 @@ func (ms *movesSearched) add(move Move) {
 	ms.length++
 }
 
+// contains returns if the move passed is in the movesSearched list
+func (ms *movesSearched) contains(move Move) bool {
+	return slices.Contains(ms.moves[:ms.length], move)
+}
+
 // Killer is a list of quiet moves that produces a beta cutoff
 type Killer [2]Move
 
@@ -219,18 +365,22 @@ func (kt *KillersTable) get(ply int) (Move, Move) {
 // Stack contains the history of moves played in the current branch of the search
 type Stack struct {
 	moves      [MaxSearchDepth * 2]Move
+	pieces     [MaxSearchDepth * 2]int // Piece moved at each ply
 	staticEval [MaxSearchDepth * 2]int
+	excluded   [MaxSearchDepth * 2]Move // Move excluded from the search at each ply (singular extensions)
 }
 
-// store stores the move in the stack at the given ply
-func (s *Stack) store(move Move, ply int) {
+// store stores the move and the piece moved in the stack at the given ply
+func (s *Stack) store(move Move, piece int, ply int) {
 	s.moves[ply] = move
+	s.pieces[ply] = piece
 }
 
 // clear clears the stack
 func (s *Stack) clear() {
 	for i := range s.moves {
 		s.moves[i] = NoMove
+		s.excluded[i] = NoMove
 	}
 }
 
@@ -243,6 +393,15 @@ func (s *Stack) getPriorMove(ply int) Move {
 	return s.moves[ply-1]
 }
 
+// getPrior returns the move and the piece moved the given number of plies before the ply passed
+func (s *Stack) getPrior(ply int, pliesBack int) (Move, int) {
+	if ply < pliesBack {
+		return NoMove, NoPiece
+	}
+
+	return s.moves[ply-pliesBack], s.pieces[ply-pliesBack]
+}
+
 // CounterMoveTable is a table for storing the following move that might produce a beta cutoff
 type CounterMoveTable [2][64][64]Move
 
@@ -277,65 +436,240 @@ func (cm *CounterMoveTable) get(priorMove Move, side Color) Move {
 // IterativeDeepening performs a progressive deepening search and returns the best move
 func (s *Search) IterativeDeepening(pos *Position, maxDepth int, stdout chan string) (bestMoveScore int, bestMove string) {
 	s.clear()
+	s.TranspositionTable.newSearch()
+
+	// When the root is in the tablebases, only the moves that keep the best result are searched, and the tables
+	// are not probed inside the search
+	userSearchMoves := s.searchMoves
+	s.tbCardinality = 0
+	if s.Tablebases != nil && !s.filterRootMovesByTablebases(pos) {
+		s.tbCardinality = s.Tablebases.MaxPieces()
+	}
+
+	s.rootMoveCount = pos.positionHistory.moveCount
+	s.stdout = stdout
+	s.lastReportTime = time.Now()
+	defer func() { s.stdout = nil }()
+
+	// Weaker levels search less
+	s.Strength.newSearch()
+	maxDepth = min(maxDepth, s.Strength.maxDepth())
+	if s.Strength.Limited {
+		s.TimeControl.limitNodes(s.Strength.maxNodes())
+	}
+	s.startHelpers(pos, maxDepth)
+
+	s.debug("time " + s.TimeControl.String())
 
 	// Ensure to return a move to the GUI
-	bestMove = setDefaultMove(pos)
+	bestMove = s.setDefaultMove(pos)
+	ponderMove := ""
+
+	// MultiPV. Search each root move slot in turn, excluding the moves already found on previous slots
+	// When the strength is limited, more slots are searched to pick a (random) move from them
+	rootMovesCount := s.rootMoves(pos).length
+	multiPV := max(1, min(s.MultiPV, rootMovesCount))
+	lines := make([]rootLine, max(multiPV, min(s.Strength.candidates(), rootMovesCount)))
 
 	for d := 1; d <= maxDepth; d++ {
 		s.reset()
+		iterationStartNodes := s.nodes.Load()
+		lastScore, lastMove, lastPonderMove := bestMoveScore, bestMove, ponderMove
+		bestBranchFactor := 0.0
+
+		for pvIdx := range lines {
+			s.pvIdx = pvIdx
+			lines[pvIdx].score = s.aspirationSearch(pos, d, lines[pvIdx].score)
+			if s.TimeControl.stop.Load() {
+				break
+			}
+			lines[pvIdx].pv = s.line(pos, d)
+			if len(lines[pvIdx].pv) > 0 {
+				s.rootExcluded.add(lines[pvIdx].pv[0])
+			}
 
-		lastScore, lastMove := bestMoveScore, bestMove
-		bestMoveScore = s.aspirationSearch(pos, d, lastScore)
-		nps := int(float64(s.nodes) / time.Since(s.TimeControl.iterationStartTime).Seconds())
+			// We use the ratio of root nodes searched of the best move for time management
+			// After d > 1, due to our move ordering scheme, fist move will always be the best move/branch
+			if pvIdx == 0 {
+				bestBranchFactor = float64(s.rootNodeCounts[0]) / float64(s.nodes.Load()-iterationStartNodes)
+			}
+		}
+		iterationTime := int(time.Since(s.TimeControl.iterationStartTime).Milliseconds())
 
 		// Always use last iteration values if it was stopped due to ran out of time
-		if !s.TimeControl.stop {
-			if len(s.pvLine) > 0 {
-				bestMove = s.pvLine[0].String()
-			}
-			elapsed := s.TimeControl.elapsed()
-			stdout <- fmt.Sprintf("info depth %d seldepth %d score %s nodes %d nps %d hashfull %d time %v pv %v",
-				d, s.seldepth, convertScore(bestMoveScore, d), s.nodes, nps, s.TranspositionTable.hashfull(),
-				elapsed, s.pvLine.String())
-		} else {
+		if s.TimeControl.stop.Load() {
+			s.debug("time stop during depth " + strconv.Itoa(d) + " " + s.TimeControl.String())
 			bestMove = lastMove
 			bestMoveScore = lastScore
+			ponderMove = lastPonderMove
+			break
+		}
+
+		// Due to search instability a later slot might get a better score than a previous one
+		slices.SortStableFunc(lines, func(a, b rootLine) int { return b.score - a.score })
+		bestMoveScore = lines[0].score
+		picked := lines[s.Strength.pick(lines)]
+		if len(picked.pv) > 0 {
+			bestMove = picked.pv[0].String()
+		}
+		ponderMove = ""
+		if len(picked.pv) > 1 {
+			ponderMove = picked.pv[1].String()
+		}
+		s.reportLines(pos, lines[:multiPV], d, stdout)
+
+		// Stop as soon as a mate is proven when searching for mate
+		if done, found := s.TimeControl.mateSearchDone(bestMoveScore, d); done {
+			if !found {
+				stdout <- "info string no mate in " + strconv.Itoa(s.TimeControl.limits.mate) + " found"
+			}
 			break
 		}
 
 		// If score drop is too big, we failed low. We should search more
 		scoreDrop := lastScore - bestMoveScore
 		if scoreDrop > 30 {
-			s.TimeControl.updateTime(1.2)
+			s.updateTime(1.2, "score drop "+strconv.Itoa(scoreDrop))
 		}
 
-		// We use the ratio of root nodes searched of the best move for time management, when its low,
-		// other branches could be better, so we increase time. When its good enough,
-		// we trust we have a good position, so we could stop the search earlier
-		// After d > 1, due to our move ordering scheme, fist move will always be the best move/branch
-		bestBranchFactor := float64(s.rootNodeCounts[0]) / float64(s.nodes)
+		// When the best branch factor is low, other branches could be better, so we increase time. When its
+		// good enough, we trust we have a good position, so we could stop the search earlier
+		branchFactor := strconv.FormatFloat(bestBranchFactor, 'f', 2, 64)
 		if bestBranchFactor <= 0.5 {
-			s.TimeControl.updateTime(1.3)
+			s.updateTime(1.3, "best branch factor "+branchFactor)
 		} else if bestBranchFactor >= 0.75 {
-			s.TimeControl.updateTime(0.8)
+			s.updateTime(0.8, "best branch factor "+branchFactor)
 		}
 
 		// Check if we should stop after this iteration
 		// Assume 2.5 as branching factor
-		estimatedNextIterationTime := int((float64(s.nodes) / float64(nps)) * 1000.0 * 2.5)
+		estimatedNextIterationTime := int(float64(iterationTime) * 2.5)
 		if s.TimeControl.shouldStopSearch(estimatedNextIterationTime) {
+			s.debug("time stop after depth " + strconv.Itoa(d) + " estimated next iteration " +
+				strconv.Itoa(estimatedNextIterationTime) + " " + s.TimeControl.String())
 			break
 		}
 	}
-	stdout <- "bestmove " + bestMove
+	s.stopHelpers()
+	s.searchMoves = userSearchMoves
+	s.debugStats()
+	s.TimeControl.waitPonderHit()
+	s.searching.Store(false)
+
+	if ponderMove != "" {
+		stdout <- "bestmove " + bestMove + " ponder " + ponderMove
+	} else {
+		stdout <- "bestmove " + bestMove
+	}
 
 	return
 }
 
+// updateTime updates the search time by the factor, reporting the reason in debug mode
+func (s *Search) updateTime(factor float64, reason string) {
+	s.TimeControl.updateTime(factor)
+	s.debug("time factor " + strconv.FormatFloat(factor, 'f', 2, 64) + " (" + reason + ") " + s.TimeControl.String())
+}
+
+// debug sends the message as an info string to the GUI when debug mode is on
+func (s *Search) debug(message string) {
+	if s.Debug && s.stdout != nil {
+		s.stdout <- "info string " + message
+	}
+}
+
+// debugStats sends the transposition table and pawn hash table stats of the search in debug mode
+func (s *Search) debugStats() {
+	if !s.Debug {
+		return
+	}
+	for line := range strings.SplitSeq(s.TranspositionTable.Stats(s.TTStats()), "\n") {
+		s.debug("tt " + line)
+	}
+	for line := range strings.SplitSeq(s.Evaluation.PawnCache.Stats(), "\n") {
+		s.debug("pawn cache " + line)
+	}
+}
+
+// rootLine is the principal variation and score found for a root move slot in multipv mode
+type rootLine struct {
+	score int
+	pv    pvLine
+}
+
+// reportLines sends the info of the lines found in the last iteration to the GUI
+func (s *Search) reportLines(pos *Position, lines []rootLine, depth int, stdout chan string) {
+	for i, line := range lines {
+		if len(line.pv) == 0 {
+			continue
+		}
+		stdout <- s.infoLine(pos, depth, i+1, line.score, "", line.pv)
+	}
+	s.lastReportTime = time.Now()
+}
+
+// reportBound sends the info of an aspiration window fail to the GUI. Only reported on long searches, to
+// avoid flooding the GUI with lines on the first iterations
+func (s *Search) reportBound(pos *Position, depth int, score int, bound string) {
+	if s.stdout == nil || s.TimeControl.elapsed() < InfoReportInterval {
+		return
+	}
+	s.stdout <- s.infoLine(pos, depth, s.pvIdx+1, score, bound, s.line(pos, 0))
+	s.lastReportTime = time.Now()
+}
+
+// reportCurrentMove sends the root move being searched to the GUI on long searches
+func (s *Search) reportCurrentMove(depth int, move Move, moveNumber int) {
+	if s.stdout == nil || s.TimeControl.elapsed() < InfoReportInterval {
+		return
+	}
+	s.stdout <- fmt.Sprintf("info depth %d currmove %v currmovenumber %d", depth, move.String(), moveNumber)
+}
+
+// reportProgress sends the nodes, nps and hashfull to the GUI periodically, so long iterations don't look frozen
+func (s *Search) reportProgress() {
+	if s.stdout == nil || time.Since(s.lastReportTime).Milliseconds() < InfoReportInterval {
+		return
+	}
+	elapsed := s.TimeControl.elapsed()
+	nodes := s.totalNodes()
+	nps := nodes * 1000 / uint64(max(elapsed, 1))
+
+	s.stdout <- fmt.Sprintf("info nodes %d nps %d hashfull %d time %v", nodes, nps, s.TranspositionTable.hashfull(), elapsed)
+	s.lastReportTime = time.Now()
+}
+
+// infoLine returns the uci info string of a line found in the search. Bound is either empty for exact
+// scores, or lowerbound/upperbound when the search failed high/low
+func (s *Search) infoLine(pos *Position, depth int, multiPV int, score int, bound string, pv pvLine) string {
+	elapsed := s.TimeControl.elapsed()
+	nodes := s.totalNodes()
+	nps := nodes * 1000 / uint64(max(elapsed, 1))
+
+	scoreString := convertScore(score, depth)
+	if s.ShowWDL {
+		scoreString += " " + convertWDL(score, pos.gamePhase())
+	}
+	if bound != "" {
+		scoreString += " " + bound
+	}
+
+	info := fmt.Sprintf("info depth %d seldepth %d multipv %d score %s nodes %d nps %d hashfull %d",
+		depth, s.seldepth, multiPV, scoreString, nodes, nps, s.TranspositionTable.hashfull())
+	if s.Tablebases != nil {
+		info += fmt.Sprintf(" tbhits %d", s.totalTBHits())
+	}
+	info += fmt.Sprintf(" time %v", elapsed)
+	if len(pv) > 0 {
+		info += " pv " + pv.String()
+	}
+	return info
+}
+
 // aspirationSearch performs aspiration window search
 func (s *Search) aspirationSearch(pos *Position, depth int, lastScore int) int {
 	if depth < 4 {
-		return s.negamax(pos, depth, 0, MinInt, MaxInt, &s.pvLine, true)
+		return s.negamax(pos, depth, 0, MinInt, MaxInt, true)
 	}
 
 	delta := AspirationWindowSize
@@ -343,18 +677,24 @@ func (s *Search) aspirationSearch(pos *Position, depth int, lastScore int) int {
 	beta := lastScore + delta
 
 	for {
-		score := s.negamax(pos, depth, 0, alpha, beta, &s.pvLine, true)
+		score := s.negamax(pos, depth, 0, alpha, beta, true)
 
 		// Score falls inside the aspiration window
 		if score > alpha && score < beta {
 			return score
 		}
 
+		if s.TimeControl.stop.Load() {
+			return score
+		}
+
 		// Adjust window size if fail
 		if score <= alpha {
+			s.reportBound(pos, depth, score, "upperbound")
 			alpha = max(alpha-delta, MinInt)
 			delta *= 2
 		} else if score >= beta {
+			s.reportBound(pos, depth, score, "lowerbound")
 			beta = min(beta+delta, MaxInt)
 			delta *= 2
 		}
@@ -365,41 +705,81 @@ func (s *Search) aspirationSearch(pos *Position, depth int, lastScore int) int {
 			beta = MaxInt
 		}
 
-		s.pvLine.reset()
+		s.pvTable.clear(0)
 	}
 }
 
-// setDefaultMove move returns the first legal move or the uci default null move (0000)
-func setDefaultMove(pos *Position) string {
-	pd := pos.generatePositionData()
-	ml := NewMoveList()
-	pos.generateNoisy(ml, &pd)
-	pos.generateQuiets(ml, &pd)
+// setDefaultMove move returns the first legal root move or the uci default null move (0000)
+func (s *Search) setDefaultMove(pos *Position) string {
+	ml := s.rootMoves(pos)
 	if ml.length > 0 {
 		return ml.moves[0].String()
 	}
 	return "0000"
 }
 
+// rootMoves returns a move list with the legal moves to search at the root, restricted to the searchmoves if any
+func (s *Search) rootMoves(pos *Position) *MoveList {
+	ml := legalMoves(pos)
+	if s.searchMoves.length == 0 {
+		return ml
+	}
+
+	rootMoves := NewMoveList()
+	for _, move := range ml.moves[:ml.length] {
+		if s.searchMoves.contains(move) {
+			rootMoves.add(move)
+		}
+	}
+	return rootMoves
+}
+
+// legalMoves returns a move list with all the legal moves of the position
+func legalMoves(pos *Position) *MoveList {
+	ml := NewMoveList()
+	generateLegalMoves(pos, ml)
+	return ml
+}
+
+// generateLegalMoves replaces the moves of the list passed with all the legal moves of the position
+func generateLegalMoves(pos *Position, ml *MoveList) {
+	pd := pos.generatePositionData()
+	ml.length = 0
+	pos.generateNoisy(ml, &pd)
+	pos.generateQuiets(ml, &pd)
+}
+
 // negamax returns the score of the best posible move by the evaluation function for a fixed depth
-func (s *Search) negamax(pos *Position, depth int, ply int, alpha int, beta int, pvLine *pvLine, nullMoveAllowed bool) int {
-	s.nodes++
-	pvLine.reset()
+func (s *Search) negamax(pos *Position, depth int, ply int, alpha int, beta int, nullMoveAllowed bool) int {
+	s.nodes.Add(1)
+	s.pvTable.clear(ply)
 
 	// Time check
-	if s.TimeControl.shouldStop() {
+	if s.TimeControl.shouldStop(s.limitedNodes()) {
 		return 0
 	}
+	if s.nodes.Load()&255 == 0 {
+		s.reportProgress()
+	}
 
 	rootNode := ply == 0
 	// Avoid these on root nodes, because otherwise, we'll not be able to return a move
 	if !rootNode {
 		// If the position is either a draw by repetition, 50 move rule or insuficient material
 		// stop inmediatly, to prevent redundant search
-		if pos.isDraw() {
+		if pos.isDraw(s.rootMoveCount) {
 			return 0
 		}
 
+		// Upcoming repetition. If the side to move can repeat a position of the search with a reversible move,
+		// the score is at least a draw, so alpha can be raised before searching the repeating move
+		if alpha < 0 && pos.hasUpcomingRepetition(s.rootMoveCount) {
+			alpha = 0
+			if alpha >= beta {
+				return beta
+			}
+		}
+
 		// Mate Distance Pruning. Cut the trees and ajust alpha/beta bounds of lines where no shorter mate is possible
 		alpha = max(alpha, -MateScore+ply)
 		beta = min(beta, MateScore-ply-1)
@@ -410,21 +790,48 @@ func (s *Search) negamax(pos *Position, depth int, ply int, alpha int, beta int,
 
 	isCheck := pos.Check(pos.Turn)
 	if depth <= 0 && !isCheck {
-		return Quiescent(pos, s, alpha, beta, ply)
+		return Quiescent(pos, s, alpha, beta, ply, 0)
 	}
 
 	pvNode := beta-alpha > 1
+	excludedMove := s.stack.excluded[ply]
+	// Later multipv slots search the root without the best moves, so they don't overwrite its entry
+	rootMovesExcluded := rootNode && s.pvIdx > 0
 
 	// Transposition Table probe. If we already have searched this position with a sufficient depth
 	// we will trust our previous evaluation and return the score
-	ttScore, ttEval, ttMove, ttHit := s.TranspositionTable.probe(pos.Hash, depth, ply, alpha, beta)
-	if ttHit && !pvNode {
+	// When a move is excluded the stored score can't be trusted, as it might come from the excluded move
+	ttScore, ttEval, ttMove, ttHit, ttEntry := s.TranspositionTable.probe(&s.ttStats, pos.Hash, depth, ply, alpha, beta)
+	if ttHit && !pvNode && excludedMove == NoMove {
 		return ttScore
 	}
 
+	// Tablebases probe. Positions with few pieces are scored by the wdl tables right after a capture or a pawn move
+	pieces := (^pos.EmptySquares()).count()
+	if !rootNode && excludedMove == NoMove && pos.halfmoveClock == 0 && s.tbCanProbe(pos, s.tbCardinality) &&
+		(pieces < s.tbCardinality || depth >= s.SyzygyProbeDepth) {
+		if wdl, state := s.probeWDL(pos, false); state != tbFailed {
+			score, tbFlag := tbScore(wdl, ply)
+			if tbFlag == FlagExact || (tbFlag == FlagBeta && score >= beta) || (tbFlag == FlagAlpha && score <= alpha) {
+				s.TranspositionTable.store(&s.ttStats, pos.Hash, min(MaxSearchDepth-1, depth+6), ply, tbFlag, score, 0, NoMove)
+				return max(alpha, min(beta, score))
+			}
+		}
+	}
+
+	// Endgame tables probe. Endings of a few pieces against a lone king are scored by their distance to mate
+	if !rootNode && excludedMove == NoMove {
+		if wdl, plies, found := s.Evaluation.Endgames.probe(pos); found {
+			score := endgameScore(wdl, plies, ply)
+			s.TranspositionTable.store(&s.ttStats, pos.Hash, min(MaxSearchDepth-1, depth+6), ply, FlagExact, score, 0, NoMove)
+			return max(alpha, min(beta, score))
+		}
+	}
+
 	flag := FlagAlpha
-	branchPv := NewPvLine(depth)
-	staticEval := s.evaluate(pos, ttMove, ttEval)
+	// The raw eval is stored in the tt, and the corrected one is used for pruning decisions
+	rawEval := s.evaluate(pos, ttMove, ttEval)
+	staticEval := s.correctEval(pos, rawEval)
 	s.stack.staticEval[ply] = staticEval
 
 	// Improving. When we are getting a better position than 2ply before(same side to move), we can
@@ -435,21 +842,30 @@ func (s *Search) negamax(pos *Position, depth int, ply int, alpha int, beta int,
 	}
 
 	// Reverse Futility Pruning / Static Null Move pruning
-	if depth <= 8 && !isCheck && !pvNode {
+	if depth <= 8 && !isCheck && !pvNode && excludedMove == NoMove {
 		margin := ReverseFutitlityPruningMargin*depth - improving*65
 		if staticEval-margin >= beta {
 			return beta
 		}
 	}
 
+	// Razoring. If the static eval is far below alpha near the horizon, we verify with a quiescence search
+	// that the position is really bad, and if it can't raise alpha we prune the node
+	if depth <= RazoringDepth && !isCheck && !pvNode && excludedMove == NoMove && staticEval+RazoringMargin*depth <= alpha {
+		score := Quiescent(pos, s, alpha, alpha+1, ply, 0)
+		if score <= alpha {
+			return alpha
+		}
+	}
+
 	// Null Move Pruning. Gives a free shot to the opponent by passing the turn.
 	// If we still exceed beta in the reduced search, we will trust our position is so good,
 	// that it will also exceed beta if we search all moves.
-	if depth >= NullMovePruningDepth && !isCheck && nullMoveAllowed && !pvNode && staticEval >= beta && !pos.kingAndPawnsOnlyEndgame() {
+	if depth >= NullMovePruningDepth && !isCheck && nullMoveAllowed && !pvNode && excludedMove == NoMove && staticEval >= beta && !pos.kingAndPawnsOnlyEndgame() {
 		ep := pos.makeNullMove()
-		s.stack.store(NoMove, ply)
+		s.stack.store(NoMove, NoPiece, ply)
 		R := 4 + depth/3
-		sc := -s.negamax(pos, depth-R, ply+1, -beta, -beta+1, &branchPv, false)
+		sc := -s.negamax(pos, depth-R, ply+1, -beta, -beta+1, false)
 		pos.unmakeNullMove(ep)
 
 		if sc >= beta {
@@ -464,15 +880,68 @@ func (s *Search) negamax(pos *Position, depth int, ply int, alpha int, beta int,
 		futilityPruningAllowed = staticEval+FutilityPruningMargin[depth] <= alpha
 	}
 
+	// ProbCut. If a good capture beats beta by a margin with a reduced search, we can assume the full depth search
+	// would also fail high. Captures are first verified with a quiescence search and then with the reduced search.
+	// Skipped when the tt already tells that a search with a similar depth did not reach the raised bound
+	probCutBeta := beta + ProbCutMargin
+	if depth >= ProbCutDepth && !pvNode && !isCheck && excludedMove == NoMove && abs(beta) < MateScore-MaxSearchDepth &&
+		!(ttEntry.depth() >= depth-3 && adjustMateScoreFromTT(ttEntry.score(), ply) < probCutBeta) {
+		noMove := NoMove
+		probCutMg := NewMoveGenerator(pos, &noMove, &noMove, &noMove, &noMove, &s.quietHistory, &s.noisyHistory, true)
+
+		for move := probCutMg.nextMove(); move != NoMove && probCutMg.stage == NoisyStage; move = probCutMg.nextMove() {
+			// The see value of the good captures is already computed by the move generator (see canPruneBySEE)
+			if staticEval+probCutMg.moves.scores[probCutMg.moves.length] < probCutBeta {
+				continue
+			}
+
+			piece := pos.PieceAt(move.from())
+			pos.MakeMove(&move)
+			s.stack.store(move, piece, ply)
+
+			score := -Quiescent(pos, s, -probCutBeta, -probCutBeta+1, ply+1, 0)
+			if score >= probCutBeta {
+				score = -s.negamax(pos, depth-ProbCutReduction, ply+1, -probCutBeta, -probCutBeta+1, true)
+			}
+			pos.UnmakeMove(&move)
+
+			if score >= probCutBeta {
+				s.TranspositionTable.store(&s.ttStats, pos.Hash, depth-ProbCutReduction+1, ply, FlagBeta, beta, rawEval, move)
+				return beta
+			}
+		}
+	}
+
 	// Internal Iterative Deepening
 	// If we dont have a move from the transposition table, make a reduced search to find a good
 	// move to improve our move ordering
 	if depth > 5 && pvNode && ttMove == NoMove {
-		s.negamax(pos, depth/2, ply+1, alpha, beta, &branchPv, true)
-		if len(branchPv) > 0 {
-			ttMove = branchPv[0]
+		s.negamax(pos, depth/2, ply+1, alpha, beta, true)
+		ttMove = s.pvTable.bestMove(ply + 1)
+	}
+
+	// Singular Extensions. If the hash move is a lower bound at a sufficient depth, we search the other moves with
+	// a reduced depth and a window below the tt score. If all of them fail low, the hash move is singular (much better
+	// than the others) so we extend it. If they also beat beta, several moves would produce a cutoff, so we can
+	// trust the node will fail high and prune it (Multi-Cut)
+	singularExtension := false
+	if !rootNode && depth >= SingularExtensionDepth && ttMove != NoMove && excludedMove == NoMove &&
+		ttEntry.depth() >= depth-3 && ttEntry.flag() != FlagAlpha {
+		ttEntryScore := adjustMateScoreFromTT(ttEntry.score(), ply)
+
+		if abs(ttEntryScore) < MateScore-MaxSearchDepth {
+			singularBeta := ttEntryScore - SingularMargin*depth
+			s.stack.excluded[ply] = ttMove
+			singularScore := s.negamax(pos, (depth-1)/2, ply, singularBeta-1, singularBeta, false)
+			s.stack.excluded[ply] = NoMove
+			s.pvTable.clear(ply)
+
+			if singularScore < singularBeta {
+				singularExtension = true
+			} else if singularBeta >= beta {
+				return singularBeta
+			}
 		}
-		branchPv.reset()
 	}
 
 	newScore := MinInt
@@ -480,11 +949,17 @@ func (s *Search) negamax(pos *Position, depth int, ply int, alpha int, beta int,
 	cm := s.counterMovesTable.get(s.stack.getPriorMove(ply), pos.Turn)
 	k1, k2 := s.killers.get(ply)
 	mg := NewMoveGenerator(pos, &ttMove, &k1, &k2, &cm, &s.quietHistory, &s.noisyHistory, false)
+	mg.excludedMove = excludedMove
+	conts := s.continuationHistories(ply)
+	mg.continuations = conts
+	if rootNode {
+		mg.excluded = &s.rootExcluded
+		mg.allowed = &s.searchMoves
+	}
 	quietsSearched := movesSearched{}
 	noisySearched := movesSearched{}
 
 	for move := mg.nextMove(); move != NoMove; move = mg.nextMove() {
-		branchPv.reset()
 		moveFlag := move.flag()
 
 		// Late Move Pruning
@@ -510,47 +985,66 @@ func (s *Search) negamax(pos *Position, depth int, ply int, alpha int, beta int,
 			noisySearched.add(move)
 		}
 
+		if rootNode {
+			s.reportCurrentMove(depth, move, mg.moveNumber+1)
+		}
+
+		piece := pos.PieceAt(move.from())
+		history := 0
+		if moveFlag < capture {
+			history = s.quietHistory[pos.Turn][move.from()][move.to()] + conts.score(piece, move.to())
+		} else {
+			history = s.noisyHistory.get(&move, pos)
+		}
+
 		pos.MakeMove(&move)
-		s.stack.store(move, ply)
+		s.stack.store(move, piece, ply)
 
 		// Keep track of nodes searched before this move
-		nodesBefore := s.nodes
+		nodesBefore := s.nodes.Load()
 
 		extension := 0
-		if isCheck {
+		if isCheck || (singularExtension && move == ttMove) {
 			extension = 1
 		}
 
 		// Principal Variation Search
 		// Full search at first attempt of this subtree
 		if mg.moveNumber == 0 {
-			newScore = -s.negamax(pos, depth-1+extension, ply+1, -beta, -alpha, &branchPv, true)
+			newScore = -s.negamax(pos, depth-1+extension, ply+1, -beta, -alpha, true)
 		} else {
 			// Try first a quick, reduced search, with lmr and a null window(-alpha-1, -alpha)
-			reduction := lmrReductionFactor(depth, mg.moveNumber, mg.stage, moveFlag, isCheck, pvNode)
-			newScore = -s.negamax(pos, depth-1-reduction+extension, ply+1, -alpha-1, -alpha, &branchPv, true)
+			reduction := lmrReductionFactor(depth, mg.moveNumber, mg.stage, moveFlag, history, isCheck, pvNode)
+			newScore = -s.negamax(pos, max(depth-1-reduction+extension, 0), ply+1, -alpha-1, -alpha, true)
 
 			// If an improvement was found, we need to search again with a full window and depth
 			if newScore > alpha {
-				newScore = -s.negamax(pos, depth-1+extension, ply+1, -beta, -alpha, &branchPv, true)
+				newScore = -s.negamax(pos, depth-1+extension, ply+1, -beta, -alpha, true)
 			}
 		}
 		pos.UnmakeMove(&move)
 
 		// Update node count at root
 		if ply == 0 {
-			s.rootNodeCounts[mg.moveNumber] += s.nodes - nodesBefore
+			s.rootNodeCounts[mg.moveNumber] += int(s.nodes.Load() - nodesBefore)
 		}
 
 		if newScore >= beta {
-			s.TranspositionTable.store(pos.Hash, depth, ply, FlagBeta, beta, staticEval, move)
+			if excludedMove == NoMove && !rootMovesExcluded {
+				s.TranspositionTable.store(&s.ttStats, pos.Hash, depth, ply, FlagBeta, beta, rawEval, move)
+				if !isCheck {
+					s.updateCorrectionHistory(pos, depth, FlagBeta, beta, staticEval, rawEval, move)
+				}
+			}
 			s.killers.store(ply, move)
 			s.counterMovesTable.store(s.stack.getPriorMove(ply), move, pos.Turn)
 			s.quietHistory.update(depth*depth, &move, pos.Turn)
 			// Reduce history score for previous 'quiet' moves that did not produce the cutoff
 			s.quietHistory.decrement(&quietsSearched, &move, depth, pos.Turn)
+			conts.update(depth*depth, &move, pos)
+			conts.decrement(&quietsSearched, &move, depth, pos)
 
-			s.noisyHistory.update(depth*depth, &move, pos)
+			s.noisyHistory.update(depth*depth*CaptureHistoryBonusScale, &move, pos)
 			s.noisyHistory.decrement(&noisySearched, &move, depth, pos)
 
 			return beta
@@ -561,17 +1055,26 @@ func (s *Search) negamax(pos *Position, depth int, ply int, alpha int, beta int,
 			bestMove = move
 
 			alpha = newScore
-			pvLine.insert(move, &branchPv)
+			s.pvTable.update(ply, move)
 		}
 	}
 
+	// With a move excluded, the node is not the same as the full node, so it's not stored. If the
+	// excluded move was the only legal move, the node just fails low
+	if excludedMove != NoMove || rootMovesExcluded {
+		return alpha
+	}
+
 	score, checkmateOrStealmateFound := isCheckmateOrStealmate(isCheck, mg.moveNumber, ply)
 	if checkmateOrStealmateFound {
-		s.TranspositionTable.store(pos.Hash, depth, ply, FlagExact, score, staticEval, NoMove)
+		s.TranspositionTable.store(&s.ttStats, pos.Hash, depth, ply, FlagExact, score, rawEval, NoMove)
 		return score
 	}
 
-	s.TranspositionTable.store(pos.Hash, depth, ply, flag, alpha, staticEval, bestMove)
+	s.TranspositionTable.store(&s.ttStats, pos.Hash, depth, ply, flag, alpha, rawEval, bestMove)
+	if !isCheck {
+		s.updateCorrectionHistory(pos, depth, flag, alpha, staticEval, rawEval, bestMove)
+	}
 	return alpha
 }
 
@@ -594,7 +1097,8 @@ func (s *Search) evaluate(pos *Position, ttMove Move, ttEval int) int {
 	return s.Evaluation.Evaluate(pos)
 }
 
-// canPruneBySEE returns if the move passed can be pruned by SEE
+// canPruneBySEE returns if the move passed can be pruned by SEE. Captures with a good capture history are allowed
+// to lose more material, as they have produced cutoffs before
 func canPruneBySEE(mg *MoveGenerator, move Move, depth int) bool {
 	see, threshold := 0, 0
 
@@ -604,7 +1108,8 @@ func canPruneBySEE(mg *MoveGenerator, move Move, depth int) bool {
 	} else {
 		// For captures we use the computed value for see in the move generator, already computed for move ordering
 		// As current move is swapped to the end of the list, we can access directly to the score(the see value)
-		see, threshold = mg.moves.scores[mg.moves.length], -depth*SEECaptureMargin
+		captureHistory := mg.noisyHistory.get(&move, mg.pos)
+		see, threshold = mg.moves.scores[mg.moves.length], -depth*SEECaptureMargin-captureHistory/SEECaptureHistoryDivisor
 	}
 
 	return see < threshold
@@ -619,7 +1124,7 @@ func (pos *Position) kingAndPawnsOnlyEndgame() bool {
 }
 
 // lrmReductionFactor returns a number to reduce the depth on search based on the conditions passed
-func lmrReductionFactor(depth, moveNumber, stage, moveFlag int, isCheck, pvNode bool) int {
+func lmrReductionFactor(depth, moveNumber, stage, moveFlag, history int, isCheck, pvNode bool) int {
 	if isCheck || depth < 3 || moveNumber < 1 {
 		return 0
 	}
@@ -630,9 +1135,9 @@ func lmrReductionFactor(depth, moveNumber, stage, moveFlag int, isCheck, pvNode
 		reduction--
 	}
 
-	// Reduces less on captures/promotions
+	// Reduces less on captures/promotions, and even less the ones with a good capture history
 	if moveFlag >= capture {
-		reduction--
+		reduction -= 1 + history/LMRCaptureHistoryDivisor
 	}
 
 	// Reduce less on Killers and counter moves
@@ -640,6 +1145,11 @@ func lmrReductionFactor(depth, moveNumber, stage, moveFlag int, isCheck, pvNode
 		reduction--
 	}
 
+	// Reduce less quiet moves with a good history (quiet and continuation histories), and more the bad ones
+	if moveFlag < capture {
+		reduction -= history / LMRHistoryDivisor
+	}
+
 	return max(reduction, 0)
 }
 
@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInfoLineWithBound(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString(StartingFenString)
+	s := NewSearch()
+	s.TimeControl.Initialize(DepthStrategy, int(pos.Turn), pos.FullMoveNumber, Clock{})
+
+	got := s.infoLine(pos, 5, 1, 40, "lowerbound", pvLine{})
+
+	if !strings.Contains(got, "score cp 40 lowerbound") || strings.Contains(got, " pv") {
+		t.Errorf("Expected: %v, got: %v", "score cp 40 lowerbound", got)
+	}
+}
+
+func TestReportCurrentMoveOnlyOnLongSearches(t *testing.T) {
+	s := NewSearch()
+	s.TimeControl.Initialize(DepthStrategy, 0, 1, Clock{})
+	s.stdout = make(chan string, 1)
+
+	s.reportCurrentMove(1, *encodeMove(12, 28, doublePawnPush), 1)
+
+	expected := 0
+	got := len(s.stdout)
+
+	if expected != got {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
+
+func TestDebugSendsDiagnostics(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString(StartingFenString)
+	s := NewSearch()
+	s.Debug = true
+	s.TimeControl.Initialize(DepthStrategy, int(pos.Turn), pos.FullMoveNumber, Clock{})
+
+	stdout := make(chan string, 1000)
+	s.IterativeDeepening(pos, 3, stdout)
+	close(stdout)
+
+	expected := []string{"info string time strategy depth", "info string tt Hitrate", "info string pawn cache Hitrate"}
+	for _, e := range expected {
+		found := false
+		for line := range stdout {
+			if strings.HasPrefix(line, e) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("Expected: %v, got: %v", e, "no line found")
+		}
+	}
+}
+
+func TestNegamaxWithOnlyLegalMoveExcludedFailsLow(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString("k7/8/8/8/8/8/1q6/K7 w - - 0 1") // Kxb2 is the only legal move
+	s := NewSearch()
+	s.TimeControl.Initialize(DepthStrategy, int(pos.Turn), pos.FullMoveNumber, Clock{})
+	s.stack.excluded[1] = *encodeMove(0, 9, capture)
+
+	expected := -50
+	got := s.negamax(pos, 2, 1, expected, expected+1, false)
+
+	if expected != got {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
+
+func TestProbCutStoresTheCaptureThatBeatsBeta(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString("4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1") // Rxd5 wins the queen
+	s := NewSearch()
+	s.TimeControl.Initialize(DepthStrategy, int(pos.Turn), pos.FullMoveNumber, Clock{})
+
+	depth := ProbCutDepth + 1
+	s.negamax(pos, depth, 1, -1, 0, true)
+	_, _, ttMove, _, entry := s.TranspositionTable.probe(&s.ttStats, pos.Hash, 0, 1, -1, 0)
+
+	expectedMove := *encodeMove(11, 35, capture)
+	if ttMove != expectedMove {
+		t.Errorf("Expected: %v, got: %v", expectedMove.String(), ttMove.String())
+	}
+	if entry.flag() != FlagBeta || entry.depth() != depth-ProbCutReduction+1 {
+		t.Errorf("Expected: %v, got: %v", []int{int(FlagBeta), depth - ProbCutReduction + 1}, []int{int(entry.flag()), entry.depth()})
+	}
+}
+
+func TestMultiPVKeepsTheBestMoveOnTheRootEntry(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString("4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1") // Rxd5 wins the queen
+	s := NewSearch()
+	s.MultiPV = 3
+	s.TimeControl.Initialize(DepthStrategy, int(pos.Turn), pos.FullMoveNumber, Clock{})
+
+	stdout := make(chan string, 1000)
+	s.IterativeDeepening(pos, 4, stdout)
+
+	expected := *encodeMove(11, 35, capture)
+	got := s.TranspositionTable.hashMove(pos.Hash)
+
+	if expected != got {
+		t.Errorf("Expected: %v, got: %v", expected.String(), got.String())
+	}
+}
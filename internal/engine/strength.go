@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"math"
+	"math/rand/v2"
+)
+
+const (
+	// Elo range allowed when the strength is limited
+	MinElo = 1320
+	MaxElo = 2800
+
+	// Number of root moves searched to pick from when the strength is limited
+	StrengthCandidates = 4
+)
+
+// Strength limits the playing strength of the engine to an approximate elo level, by capping the
+// search depth and nodes, and adding some randomness to the root move selection
+type Strength struct {
+	Limited bool
+	Elo     int
+	Seed    uint64 // Seed for the random move selection. With 0 a random seed is used on each search
+	rng     *rand.Rand
+}
+
+// NewStrength returns a new Strength with full strength
+func NewStrength() Strength {
+	return Strength{Elo: MaxElo}
+}
+
+// SetElo sets the elo level, clamped to the allowed range
+func (st *Strength) SetElo(elo int) {
+	st.Elo = max(MinElo, min(elo, MaxElo))
+}
+
+// newSearch prepares the random generator for a new search. When a seed is set, the same
+// search will always pick the same move
+func (st *Strength) newSearch() {
+	seed := st.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	st.rng = rand.New(rand.NewPCG(seed, seed))
+}
+
+// maxDepth returns the max depth allowed to search at the current elo level
+func (st *Strength) maxDepth() int {
+	if !st.Limited {
+		return MaxSearchDepth
+	}
+	return 1 + (st.Elo-MinElo)*14/(MaxElo-MinElo)
+}
+
+// maxNodes returns the max nodes allowed to search at the current elo level, doubling every 150 elo
+func (st *Strength) maxNodes() uint64 {
+	return uint64(1000 * math.Pow(2, float64(st.Elo-MinElo)/150))
+}
+
+// candidates returns the number of root moves to search to pick the move from
+func (st *Strength) candidates() int {
+	if !st.Limited {
+		return 1
+	}
+	return StrengthCandidates
+}
+
+// noise returns the max random score in centipawns added to the root moves at the current elo level
+func (st *Strength) noise() int {
+	return (MaxElo - st.Elo) / 5
+}
+
+// pick returns the index of the line to play. With full strength is always the best line, otherwise
+// a random bonus is added to each line score, so weaker levels might pick worse moves
+func (st *Strength) pick(lines []rootLine) (picked int) {
+	if !st.Limited || st.noise() == 0 {
+		return 0
+	}
+
+	bestScore := MinInt
+	for i, line := range lines {
+		if len(line.pv) == 0 {
+			continue
+		}
+		score := line.score + st.rng.IntN(st.noise()+1)
+		if score > bestScore {
+			bestScore = score
+			picked = i
+		}
+	}
+	return
+}
@@ -0,0 +1,56 @@
+package engine
+
+import "testing"
+
+func TestStrengthPickIsDeterministicWithSeed(t *testing.T) {
+	lines := []rootLine{{score: 30, pv: pvLine{1}}, {score: 20, pv: pvLine{2}}, {score: 10, pv: pvLine{3}}, {score: 0, pv: pvLine{4}}}
+	st := NewStrength()
+	st.Limited = true
+	st.SetElo(MinElo)
+	st.Seed = 42
+
+	st.newSearch()
+	expected := []int{st.pick(lines), st.pick(lines), st.pick(lines)}
+	st.newSearch()
+	got := []int{st.pick(lines), st.pick(lines), st.pick(lines)}
+
+	for i := range expected {
+		if expected[i] != got[i] {
+			t.Errorf("Expected: %v, got: %v", expected, got)
+		}
+	}
+}
+
+func TestStrengthNotLimitedPicksBestLine(t *testing.T) {
+	lines := []rootLine{{score: 30, pv: pvLine{1}}, {score: 20, pv: pvLine{2}}}
+	st := NewStrength()
+	st.newSearch()
+
+	expected := 0
+	got := st.pick(lines)
+
+	if expected != got {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
+
+func TestLimitedStrengthSearchIsDeterministicWithSeed(t *testing.T) {
+	bestMoves := []string{}
+	for range 2 {
+		pos := NewPosition()
+		pos.LoadFromFenString(StartingFenString)
+		s := NewSearch()
+		s.Strength.Limited = true
+		s.Strength.SetElo(1600)
+		s.Strength.Seed = 7
+		s.TimeControl.Initialize(InfiniteStrategy, int(pos.Turn), pos.FullMoveNumber, Clock{})
+
+		stdout := make(chan string, 1000)
+		_, bestMove := s.IterativeDeepening(pos, MaxSearchDepth, stdout)
+		bestMoves = append(bestMoves, bestMove)
+	}
+
+	if bestMoves[0] != bestMoves[1] {
+		t.Errorf("Expected: %v, got: %v", bestMoves[0], bestMoves[1])
+	}
+}
@@ -0,0 +1,311 @@
+package engine
+
+import (
+	"github.com/gabtar/aconcagua/internal/syzygy"
+)
+
+// Constants to use with the tablebases
+const (
+	TBWinScore              = MateScore - 2*MaxSearchDepth // Score of a won position in the tablebases, below the mate scores
+	DefaultSyzygyProbeDepth = 1
+	MaxDTZ                  = 1 << 18 // Rank of the best root moves when ranked by their dtz
+)
+
+// tbPieces contains the tablebases code of each piece
+var tbPieces = [12]syzygy.Piece{
+	syzygy.WhiteKing, syzygy.WhiteQueen, syzygy.WhiteRook, syzygy.WhiteBishop, syzygy.WhiteKnight, syzygy.WhitePawn,
+	syzygy.BlackKing, syzygy.BlackQueen, syzygy.BlackRook, syzygy.BlackBishop, syzygy.BlackKnight, syzygy.BlackPawn,
+}
+
+// tbMoveLists are the move lists of each capture searched when probing the tablebases
+type tbMoveLists [syzygy.MaxPieces]MoveList
+
+// tbProbeState is the result of probing the tablebases with a search of the captures
+type tbProbeState int
+
+const (
+	tbFailed          tbProbeState = iota // A table is missing or it can't be read
+	tbOK                                  // The value was found
+	tbZeroingBestMove                     // The best move is a capture or a pawn move, so the dtz tables can't be probed
+)
+
+// SetSyzygyPath opens the tablebases on the directories of the path passed. Returns the number of tables found.
+// An empty path disables the tablebases
+func (s *Search) SetSyzygyPath(path string) (int, error) {
+	if s.Tablebases != nil {
+		s.Tablebases.Close()
+		s.Tablebases = nil
+	}
+	if path == "" || path == "<empty>" {
+		return 0, nil
+	}
+
+	tb, err := syzygy.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	s.Tablebases = tb
+	return tb.Count(), nil
+}
+
+// tbPosition returns the position to probe the tablebases
+func tbPosition(pos *Position) *syzygy.Position {
+	tbPos := &syzygy.Position{BlackToMove: pos.Turn == Black}
+	for piece, bb := range pos.Pieces {
+		for bb > 0 {
+			tbPos.Board[Bsf(bb.NextBit())] = tbPieces[piece]
+		}
+	}
+	return tbPos
+}
+
+// isCapture returns if the move captures a piece
+func isCapture(move Move) bool {
+	return move.flag() == capture || move.flag() == epCapture || move.flag() >= knightCapturePromotion
+}
+
+// probeWDL returns the wdl value of the position in the tablebases. Tables don't store the right value when
+// the best move is a capture, and don't take into account en passant captures, so the captures are searched
+// before probing the tables. When zeroingMoves is set, pawn moves are also searched, as dtz tables don't store
+// the right value when the best move is a pawn move
+func (s *Search) probeWDL(pos *Position, zeroingMoves bool) (syzygy.WDL, tbProbeState) {
+	return s.probeWDLAt(pos, zeroingMoves, 0)
+}
+
+// probeWDLAt returns the wdl value of the position after the number of captures passed. Each capture reuses the
+// move list of its depth, as a capture can't be followed by more captures than the pieces left on the board
+func (s *Search) probeWDLAt(pos *Position, zeroingMoves bool, depth int) (syzygy.WDL, tbProbeState) {
+	ml := &s.tbMoves[depth]
+	generateLegalMoves(pos, ml)
+	best, moveCount := syzygy.Loss, 0
+
+	for _, move := range ml.moves[:ml.length] {
+		if !isCapture(move) && (!zeroingMoves || pieceRole(pos.PieceAt(move.from())) != Pawn) {
+			continue
+		}
+		moveCount++
+
+		pos.MakeMove(&move)
+		wdl, state := s.probeWDLAt(pos, false, depth+1)
+		pos.UnmakeMove(&move)
+		if state == tbFailed {
+			return syzygy.Draw, tbFailed
+		}
+
+		if -wdl > best {
+			best = -wdl
+			if best == syzygy.Win {
+				return best, tbZeroingBestMove
+			}
+		}
+	}
+
+	// When all the moves have been searched the tables are not needed, as the value stored might be wrong
+	allMovesSearched := moveCount > 0 && moveCount == ml.length
+	value := best
+	if !allMovesSearched {
+		wdl, result := s.Tablebases.ProbeWDL(tbPosition(pos))
+		if result != syzygy.Found {
+			return syzygy.Draw, tbFailed
+		}
+		s.tbHits.Add(1)
+		value = wdl
+	}
+
+	if best >= value {
+		if best > syzygy.Draw || allMovesSearched {
+			return best, tbZeroingBestMove
+		}
+		return best, tbOK
+	}
+	return value, tbOK
+}
+
+// probeDTZ returns the distance to zeroing in plies of the position, positive when winning and negative
+// when losing. Cursed wins and blessed losses are 100 plies further
+func (s *Search) probeDTZ(pos *Position) (int, tbProbeState) {
+	wdl, state := s.probeWDL(pos, true)
+	if state == tbFailed || wdl == syzygy.Draw {
+		return 0, state
+	}
+	if state == tbZeroingBestMove {
+		return dtzBeforeZeroing(wdl), tbOK
+	}
+
+	dtz, result := s.Tablebases.ProbeDTZ(tbPosition(pos), wdl)
+	switch result {
+	case syzygy.Failed:
+		return 0, tbFailed
+	case syzygy.Found:
+		s.tbHits.Add(1)
+		if wdl == syzygy.CursedWin || wdl == syzygy.BlessedLoss {
+			dtz += 100
+		}
+		if wdl < 0 {
+			return -dtz, tbOK
+		}
+		return dtz, tbOK
+	}
+
+	// The table only stores the other side to move, so we look for the move that minimizes the dtz
+	ml := legalMoves(pos)
+	minDTZ := 0xFFFF
+	for _, move := range ml.moves[:ml.length] {
+		zeroing := isCapture(move) || pieceRole(pos.PieceAt(move.from())) == Pawn
+		pos.MakeMove(&move)
+
+		// For zeroing moves we take the dtz before the move, with the value of the position after it
+		if zeroing {
+			var childWDL syzygy.WDL
+			childWDL, state = s.probeWDL(pos, false)
+			dtz = -dtzBeforeZeroing(childWDL)
+		} else {
+			dtz, state = s.probeDTZ(pos)
+			dtz = -dtz
+		}
+
+		if dtz == 1 && pos.Check(pos.Turn) && legalMoves(pos).length == 0 {
+			minDTZ = 1
+		}
+		if !zeroing {
+			dtz += sign(dtz)
+		}
+		if dtz < minDTZ && sign(dtz) == sign(int(wdl)) {
+			minDTZ = dtz
+		}
+		pos.UnmakeMove(&move)
+
+		if state == tbFailed {
+			return 0, tbFailed
+		}
+	}
+
+	if minDTZ == 0xFFFF {
+		return -1, tbOK
+	}
+	return minDTZ, tbOK
+}
+
+// dtzBeforeZeroing returns the dtz of a position where the best move is a zeroing move
+func dtzBeforeZeroing(wdl syzygy.WDL) int {
+	switch wdl {
+	case syzygy.Win:
+		return 1
+	case syzygy.CursedWin:
+		return 101
+	case syzygy.BlessedLoss:
+		return -101
+	case syzygy.Loss:
+		return -1
+	}
+	return 0
+}
+
+func sign(x int) int {
+	if x > 0 {
+		return 1
+	}
+	if x < 0 {
+		return -1
+	}
+	return 0
+}
+
+// tbCanProbe returns if the position can be probed in the tablebases. Tables don't store castling rights
+func (s *Search) tbCanProbe(pos *Position, maxPieces int) bool {
+	return s.Tablebases != nil && pos.castling.castlingRights == noCastling &&
+		(^pos.EmptySquares()).count() <= min(maxPieces, s.Tablebases.MaxPieces())
+}
+
+// filterRootMovesByTablebases restricts the root moves to the ones that keep the best result according to the
+// dtz tables, taking into account the 50 move rule. Returns if the root position was found in the tables
+func (s *Search) filterRootMovesByTablebases(pos *Position) bool {
+	if !s.tbCanProbe(pos, syzygy.MaxPieces) {
+		return false
+	}
+
+	ml := s.rootMoves(pos)
+	ranks := make([]int, ml.length)
+	repeated := pos.hasRepeated()
+	bestRank := -MaxDTZ - MaxDTZ
+
+	for i, move := range ml.moves[:ml.length] {
+		pos.MakeMove(&move)
+		dtz, state := 0, tbOK
+
+		// The dtz is counted from the root position
+		switch {
+		case pos.halfmoveClock == 0:
+			wdl, probeState := s.probeWDL(pos, false)
+			dtz, state = dtzBeforeZeroing(-wdl), probeState
+		case pos.isThreefoldRepetition() || pos.halfmoveClock >= 100:
+			dtz = 0
+		default:
+			dtz, state = s.probeDTZ(pos)
+			dtz = -dtz
+			dtz += sign(dtz)
+		}
+
+		// Mating moves get a dtz of 1
+		if pos.Check(pos.Turn) && dtz == 2 && legalMoves(pos).length == 0 {
+			dtz = 1
+		}
+		pos.UnmakeMove(&move)
+
+		if state == tbFailed {
+			return false
+		}
+
+		// Better moves are ranked higher: faster wins and slower losses. When the 50 move rule is in sight, wins are
+		// ranked below the certain ones and losses above
+		switch {
+		case dtz > 0 && dtz+pos.halfmoveClock <= 100 && !repeated:
+			ranks[i] = MaxDTZ - dtz
+		case dtz > 0:
+			ranks[i] = MaxDTZ/2 - (dtz + pos.halfmoveClock)
+		case dtz < 0 && -dtz*2+pos.halfmoveClock < 100:
+			ranks[i] = -MaxDTZ - dtz
+		case dtz < 0:
+			ranks[i] = -MaxDTZ/2 + (-dtz + pos.halfmoveClock)
+		}
+		bestRank = max(bestRank, ranks[i])
+	}
+
+	s.searchMoves.length = 0
+	for i, move := range ml.moves[:ml.length] {
+		if ranks[i] == bestRank {
+			s.searchMoves.add(move)
+		}
+	}
+	return true
+}
+
+// hasRepeated returns if a position has been repeated since the last irreversible move
+func (pos *Position) hasRepeated() bool {
+	lastIrreversibleMove := max(pos.positionHistory.moveCount-pos.halfmoveClock, 0)
+	hash := pos.Hash
+
+	for i := pos.positionHistory.moveCount; i >= lastIrreversibleMove; i-- {
+		for j := i - 4; j >= lastIrreversibleMove; j -= 2 {
+			if pos.positionHistory.previousPosition[j] == hash {
+				return true
+			}
+		}
+		if i > 0 {
+			hash = pos.positionHistory.previousPosition[i-1]
+		}
+	}
+	return false
+}
+
+// tbScore returns the score of the wdl value found in the tablebases at the ply passed, and the bound of the score.
+// Cursed wins and blessed losses are scored close to a draw
+func tbScore(wdl syzygy.WDL, ply int) (int, uint8) {
+	switch {
+	case wdl == syzygy.Win:
+		return TBWinScore - ply, FlagBeta
+	case wdl == syzygy.Loss:
+		return -TBWinScore + ply, FlagAlpha
+	}
+	return 2 * int(wdl), FlagExact
+}
@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gabtar/aconcagua/internal/syzygy"
+)
+
+const syzygyTestdataPath = "../syzygy/testdata"
+
+func TestSetSyzygyPath(t *testing.T) {
+	s := NewSearch()
+
+	expected := 2
+	got, err := s.SetSyzygyPath(syzygyTestdataPath)
+
+	if err != nil || got != expected || s.Tablebases == nil {
+		t.Errorf("Expected: %v, got: %v (%v)", expected, got, err)
+	}
+
+	s.SetSyzygyPath("<empty>")
+	if s.Tablebases != nil {
+		t.Errorf("Expected: %v, got: %v", nil, s.Tablebases)
+	}
+}
+
+func TestProbeWDLSearchesTheCaptures(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString("k6r/8/1K6/8/8/2Q5/8/8 w - - 0 1") // Qxh8+ wins, there is no KQvKR table
+	s := NewSearch()
+	s.SetSyzygyPath(syzygyTestdataPath)
+
+	expected := syzygy.Win
+	got, state := s.probeWDL(pos, false)
+
+	if got != expected || state != tbZeroingBestMove {
+		t.Errorf("Expected: %v, got: %v (%v)", expected, got, state)
+	}
+}
+
+func TestProbeWDLReusesTheMoveLists(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString("k6r/8/1K6/8/8/2Q5/8/8 w - - 0 1")
+	s := NewSearch()
+	s.SetSyzygyPath(syzygyTestdataPath)
+
+	expected := 0.0
+	got := testing.AllocsPerRun(100, func() { s.probeWDL(pos, false) })
+
+	if got != expected {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
+
+func TestProbeDTZWithTheOtherSideToMove(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString("k7/8/1K6/8/8/8/2Q5/8 b - - 0 1") // Kb8 Qc7+ Ka8 Qa7#
+	s := NewSearch()
+	s.SetSyzygyPath(syzygyTestdataPath)
+
+	expected := -4
+	got, state := s.probeDTZ(pos)
+
+	if got != expected || state != tbOK {
+		t.Errorf("Expected: %v, got: %v (%v)", expected, got, state)
+	}
+}
+
+func TestFilterRootMovesByTablebases(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString("k7/8/1K6/8/8/8/2Q5/8 w - - 0 1") // Qc8 mates
+	s := NewSearch()
+	s.SetSyzygyPath(syzygyTestdataPath)
+
+	found := s.filterRootMovesByTablebases(pos)
+
+	expected := "c2c8"
+	if !found || s.searchMoves.length != 1 || s.searchMoves.moves[0].String() != expected {
+		t.Errorf("Expected: %v, got: %v", expected, s.searchMoves.moves[:s.searchMoves.length])
+	}
+}
+
+func TestSearchProbesTheTablebases(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString("k6r/8/1K6/8/8/2Q5/8/8 w - - 0 1") // Qxh8 wins
+	s := NewSearch()
+	s.SetSyzygyPath(syzygyTestdataPath)
+	s.TimeControl.Initialize(DepthStrategy, int(pos.Turn), pos.FullMoveNumber, Clock{})
+
+	stdout := make(chan string, 1000)
+	score, _ := s.IterativeDeepening(pos, 3, stdout)
+	close(stdout)
+
+	if score < TBWinScore-MaxSearchDepth {
+		t.Errorf("Expected: %v, got: %v", "a tablebase win", score)
+	}
+
+	tbHitsReported := false
+	for line := range stdout {
+		if strings.Contains(line, " tbhits ") && !strings.Contains(line, " tbhits 0 ") {
+			tbHitsReported = true
+		}
+	}
+	if !tbHitsReported {
+		t.Errorf("Expected: %v, got: %v", "tbhits reported", "no tbhits")
+	}
+}
+
+func TestSyzygyKPvKAgreesWithTheEndgameTables(t *testing.T) {
+	tb, err := syzygy.Open(syzygyTestdataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tb.Close()
+	tables := NewEndgameTables()
+	kpk := tables.tables[2]
+	kpk.ensure()
+
+	// Both tables are generated independently, so they must agree on the result of every legal position
+	for whiteKing := range 64 {
+		for pawn := 8; pawn < 56; pawn++ {
+			for blackKing := range 64 {
+				sq := [4]int{whiteKing, pawn, blackKing}
+				if whiteKing == pawn || blackKing == pawn || SquareDistance(whiteKing, blackKing) <= 1 {
+					continue
+				}
+				for _, whiteToMove := range []bool{true, false} {
+					pos := endgamePosition(kpk, sq, whiteToMove)
+					if pos.Check(pos.Turn.Opponent()) {
+						continue
+					}
+
+					expected, _, _ := tables.probe(pos)
+					wdl, result := tb.ProbeWDL(tbPosition(pos))
+					got := sign(int(wdl))
+
+					if got != expected || result != syzygy.Found {
+						t.Fatalf("Expected: %v, got: %v (%v) at %v", expected, got, result, pos.ToFen())
+					}
+				}
+			}
+		}
+	}
+}
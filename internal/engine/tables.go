@@ -20,6 +20,10 @@ const (
 	// notAFile/notHFile contains a bitboard mask without the A and H file
 	notAFile Bitboard = 0xfefefefefefefefe
 	notHFile Bitboard = 0x7f7f7f7f7f7f7f7f
+
+	// darkSquares/lightSquares contains a bitboard mask with the squares of each color, a1 is a dark square
+	darkSquares  Bitboard = 0xaa55aa55aa55aa55
+	lightSquares Bitboard = ^darkSquares
 )
 
 // init initializes various tables for usage within the engine
@@ -0,0 +1,127 @@
+package engine
+
+import (
+	"bufio"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// epdEntry is a test position of an epd file with the best moves (in SAN) and the search depth to find them
+type epdEntry struct {
+	fen       string
+	bestMoves []string
+	depth     int
+	id        string
+}
+
+// loadEPD loads the positions of the epd file passed
+func loadEPD(t *testing.T, path string) (entries []epdEntry) {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		entry := epdEntry{fen: strings.Join(fields[:4], " ") + " 0 1"}
+		for op := range strings.SplitSeq(strings.Join(fields[4:], " "), ";") {
+			opcode, operands, _ := strings.Cut(strings.TrimSpace(op), " ")
+			switch opcode {
+			case "bm":
+				entry.bestMoves = strings.Fields(operands)
+			case "acd":
+				entry.depth, _ = strconv.Atoi(operands)
+			case "id":
+				entry.id = strings.Trim(operands, "\"")
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return
+}
+
+// sanPieceLetters are the letters of the pieces in SAN notation, indexed by piece role
+var sanPieceLetters = [6]string{"K", "Q", "R", "B", "N", ""}
+
+// toSAN returns the move in SAN notation without check/mate annotations
+func toSAN(pos *Position, move Move) string {
+	flag := move.flag()
+	if flag == kingsideCastle {
+		return "O-O"
+	}
+	if flag == queensideCastle {
+		return "O-O-O"
+	}
+
+	role := pieceRole(pos.PieceAt(move.from()))
+	from, to := squareToString(move.from()), squareToString(move.to())
+	isCapture := flag == capture || flag == epCapture || flag >= knightCapturePromotion
+
+	san := sanPieceLetters[role]
+	if role == Pawn {
+		if isCapture {
+			san = from[:1]
+		}
+	} else {
+		sameFile, sameRank, ambiguous := false, false, false
+		ml := legalMoves(pos)
+		for _, other := range ml.moves[:ml.length] {
+			if other != move && other.to() == move.to() && pieceRole(pos.PieceAt(other.from())) == role {
+				ambiguous = true
+				sameFile = sameFile || squareToString(other.from())[0] == from[0]
+				sameRank = sameRank || squareToString(other.from())[1] == from[1]
+			}
+		}
+		if ambiguous && !sameFile {
+			san += from[:1]
+		} else if ambiguous && !sameRank {
+			san += from[1:]
+		} else if ambiguous {
+			san += from
+		}
+	}
+
+	if isCapture {
+		san += "x"
+	}
+	san += to
+	if flag >= knightPromotion {
+		san += "=" + strings.ToUpper(move.String()[4:])
+	}
+	return san
+}
+
+func TestTacticalPositions(t *testing.T) {
+	for _, entry := range loadEPD(t, "testdata/tactics.epd") {
+		pos := NewPosition()
+		pos.LoadFromFenString(entry.fen)
+		s := NewSearch()
+		s.TimeControl.Initialize(DepthStrategy, int(pos.Turn), pos.FullMoveNumber, Clock{})
+
+		stdout := make(chan string, 1000)
+		_, bestMove := s.IterativeDeepening(pos, entry.depth, stdout)
+		got := ""
+		ml := legalMoves(pos)
+		for _, move := range ml.moves[:ml.length] {
+			if move.String() == bestMove {
+				got = toSAN(pos, move)
+			}
+		}
+
+		expected := make([]string, len(entry.bestMoves))
+		for i, bm := range entry.bestMoves {
+			expected[i] = strings.TrimRight(bm, "+#")
+		}
+		if !slices.Contains(expected, got) {
+			t.Errorf("%v: Expected: %v, got: %v", entry.id, expected, got)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package engine
+
+// MaxThreads is the maximum number of search threads allowed
+const MaxThreads = 128
+
+// SetThreads sets the number of threads used in the search (Lazy SMP)
+// The main search thread is always used, so n-1 helper threads are created. Helpers share the
+// transposition table with the main thread, but keep their own history, killers, counter moves and stack
+func (s *Search) SetThreads(n int) {
+	n = max(1, min(n, MaxThreads))
+	s.helpers = make([]*Search, n-1)
+	for i := range s.helpers {
+		s.helpers[i] = newSearchWithTable(s.TranspositionTable)
+		s.helpers[i].mainThread = s
+	}
+}
+
+// Threads returns the number of threads used in the search
+func (s *Search) Threads() int {
+	return len(s.helpers) + 1
+}
+
+// ClearHash clears the transposition table and the evaluation caches of all search threads
+func (s *Search) ClearHash() {
+	s.TranspositionTable.Clear()
+	s.Evaluation.Clear()
+	s.ttStats = TTStats{}
+	for _, h := range s.helpers {
+		h.Evaluation.Clear()
+		h.ttStats = TTStats{}
+	}
+}
+
+// startHelpers starts a search on each helper thread with its own copy of the position
+func (s *Search) startHelpers(pos *Position, maxDepth int) {
+	for i, h := range s.helpers {
+		h.clear()
+		h.searchMoves = s.searchMoves
+		h.rootMoveCount = pos.positionHistory.moveCount
+		h.Tablebases = s.Tablebases
+		h.SyzygyProbeDepth = s.SyzygyProbeDepth
+		h.QuiescentChecks = s.QuiescentChecks
+		h.tbCardinality = s.tbCardinality
+		h.Evaluation.Network = s.Evaluation.Network
+		h.TimeControl.Initialize(InfiniteStrategy, int(pos.Turn), pos.FullMoveNumber, Clock{})
+		h.TimeControl.limitNodes(s.TimeControl.limits.nodes)
+		helperPos := *pos
+
+		s.helpersGroup.Add(1)
+		go func() {
+			defer s.helpersGroup.Done()
+			h.helperSearch(&helperPos, maxDepth, i+1)
+		}()
+	}
+}
+
+// stopHelpers signals the helper threads to stop and waits until all of them finished
+func (s *Search) stopHelpers() {
+	for _, h := range s.helpers {
+		h.Stop()
+	}
+	s.helpersGroup.Wait()
+}
+
+// helperSearch performs an iterative deepening search without reporting to the GUI. Helpers
+// only populate the shared transposition table, so the main thread can search deeper faster.
+// Odd helpers start one ply deeper, to desynchronize the threads and search different trees
+func (s *Search) helperSearch(pos *Position, maxDepth int, id int) {
+	score := 0
+	for d := 1 + id%2; d <= maxDepth && !s.TimeControl.stop.Load(); d++ {
+		s.reset()
+		score = s.aspirationSearch(pos, d, score)
+	}
+}
+
+// TTStats returns the usage stats of the transposition table by all the search threads
+func (s *Search) TTStats() TTStats {
+	stats := s.ttStats
+	for _, h := range s.helpers {
+		stats.add(h.ttStats)
+	}
+	return stats
+}
+
+// totalNodes returns the number of nodes searched by all the search threads
+func (s *Search) totalNodes() uint64 {
+	nodes := s.nodes.Load()
+	for _, h := range s.helpers {
+		nodes += h.nodes.Load()
+	}
+	return nodes
+}
+
+// limitedNodes returns the nodes checked against the nodes limit of the search. When there is a limit, the
+// nodes of all the threads are counted, as the limit applies to the whole search. Helpers check the limit too, so
+// they don't keep searching until the main thread stops them
+func (s *Search) limitedNodes() uint64 {
+	if s.TimeControl.limits.nodes == 0 {
+		return s.nodes.Load()
+	}
+	if s.mainThread != nil {
+		return s.mainThread.totalNodes()
+	}
+	return s.totalNodes()
+}
+
+// totalTBHits returns the number of positions found in the tablebases by all the search threads
+func (s *Search) totalTBHits() uint64 {
+	tbHits := s.tbHits.Load()
+	for _, h := range s.helpers {
+		tbHits += h.tbHits.Load()
+	}
+	return tbHits
+}
@@ -0,0 +1,55 @@
+package engine
+
+import "testing"
+
+func TestSetThreads(t *testing.T) {
+	s := NewSearch()
+	s.SetThreads(4)
+
+	expected := 4
+	got := s.Threads()
+
+	if got != expected {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+
+	for _, h := range s.helpers {
+		if h.TranspositionTable != s.TranspositionTable {
+			t.Errorf("Expected helpers to share the transposition table")
+		}
+	}
+}
+
+func TestMultiThreadedSearchReturnsBestMove(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString("6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1") // Back rank mate
+	s := NewSearch()
+	s.SetThreads(3)
+	s.TimeControl.Initialize(DepthStrategy, int(pos.Turn), pos.FullMoveNumber, Clock{})
+
+	stdout := make(chan string, 100)
+	_, bestMove := s.IterativeDeepening(pos, 4, stdout)
+
+	expected := "a1a8"
+	if bestMove != expected {
+		t.Errorf("Expected: %v, got: %v", expected, bestMove)
+	}
+}
+
+func TestNodesLimitCountsAllThreads(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString(StartingFenString)
+	s := NewSearch()
+	s.SetThreads(4)
+	s.TimeControl.Initialize(NodesStrategy, int(pos.Turn), pos.FullMoveNumber, Clock{nodes: 100000})
+
+	stdout := make(chan string, 1000)
+	s.IterativeDeepening(pos, MaxSearchDepth, stdout)
+
+	limit := uint64(101000) // Each thread might search a few more nodes until it sees the limit reached
+	got := s.totalNodes()
+
+	if got > limit {
+		t.Errorf("Expected: less than %v, got: %v", limit, got)
+	}
+}
@@ -2,6 +2,7 @@ package engine
 
 import (
 	"strconv"
+	"sync/atomic"
 	"time"
 )
 
@@ -10,23 +11,32 @@ const (
 	InfiniteStrategy        // Max depth search
 	MoveTimeStrategy        // Fixed move time
 	TimeLeftStrategy        // Tournament time control play
+	NodesStrategy           // Fixed number of nodes
+	MateStrategy            // Search for a mate in a number of moves
 )
 
+// strategyNames are the names of the time control strategies for debug output
+var strategyNames = [...]string{"depth", "infinite", "movetime", "timeleft", "nodes", "mate"}
+
 // TimeControl hanldes the time during search
 type TimeControl struct {
 	startTime          time.Time
 	iterationStartTime time.Time
 	limits             TimeLimits
 	strategy           int
-	stop               bool
-	Overhead           int // Move Overhead time in ms
+	stop               atomic.Bool
+	pondering          atomic.Bool  // While pondering the time limits are not applied until a ponderhit
+	ponderTime         atomic.Int64 // Time in ms spent pondering before the ponderhit, not counted on the limits
+	Overhead           int          // Move Overhead time in ms
 }
 
 // TimeLimits stores the soft and hard time limits in miliseconds
 type TimeLimits struct {
-	softLimit   int // Optimal time
-	hardLimit   int // Max time allowed
-	minimalTime int // Never search less than this threshold
+	softLimit   int    // Optimal time
+	hardLimit   int    // Max time allowed
+	minimalTime int    // Never search less than this threshold
+	nodes       uint64 // Max nodes allowed
+	mate        int    // Search for a mate in this number of moves
 }
 
 // Clock is the struct to store the white and black time, and increments
@@ -37,6 +47,8 @@ type Clock struct {
 	binc      int
 	moveTime  int
 	movesToGo int
+	nodes     int
+	mate      int
 }
 
 // NewTimeControl returns a pointer to a new TimeControl struct
@@ -51,6 +63,11 @@ func (tc *TimeControl) elapsed() int {
 	return int(time.Since(tc.startTime).Milliseconds())
 }
 
+// usedTime returns the time elapsed since the clock of the side started running, without the time spent pondering
+func (tc *TimeControl) usedTime() int {
+	return tc.elapsed() - int(tc.ponderTime.Load())
+}
+
 // timeLeft returns the time left for the side
 func (c *Clock) timeLeft(side Color) (timeLeft float64, increment float64) {
 	if side == Black {
@@ -64,7 +81,9 @@ func (tc *TimeControl) Initialize(strategy int, side int, moveNumber int, clock
 	tc.startTime = time.Now()
 	tc.iterationStartTime = time.Now()
 	tc.strategy = strategy
-	tc.stop = false
+	tc.stop.Store(false)
+	tc.pondering.Store(false)
+	tc.ponderTime.Store(0)
 
 	tc.setupLimits(strategy, side, moveNumber, clock)
 }
@@ -90,6 +109,8 @@ func (tc *TimeControl) setupLimits(strategy int, side int, moveNumber int, clock
 	tc.limits.softLimit = soft
 	tc.limits.hardLimit = hard
 	tc.limits.minimalTime = soft / 2
+	tc.limits.nodes = uint64(max(clock.nodes, 0))
+	tc.limits.mate = clock.mate
 }
 
 // defineLimits returns the limits for the search
@@ -102,50 +123,117 @@ func defineLimits(maxTime int, timeLeft int) (softLimit int, hardLimit int) {
 	return maxTime / 2, min(maxTime*4, timeLeft)
 }
 
+// Ponder starts pondering. The search runs with an infinite budget until a ponderhit or a stop
+func (tc *TimeControl) Ponder() {
+	tc.pondering.Store(true)
+}
+
+// PonderHit switches the search from pondering to the time limits of the clock. As the opponent
+// played the expected move, the clock starts running now, so the time spent pondering is not counted
+// It's called while the search is running, so only the atomic fields are modified
+func (tc *TimeControl) PonderHit() {
+	if !tc.pondering.Load() {
+		return
+	}
+
+	tc.ponderTime.Store(int64(tc.elapsed()))
+	tc.pondering.Store(false)
+}
+
+// waitPonderHit waits until a ponderhit or a stop is received, as the best move must not be sent while pondering
+func (tc *TimeControl) waitPonderHit() {
+	for tc.pondering.Load() && !tc.stop.Load() {
+		time.Sleep(time.Millisecond)
+	}
+}
+
 // shouldStop returns true if the search should stop
-func (tc *TimeControl) shouldStop() bool {
-	if tc.stop {
+func (tc *TimeControl) shouldStop(nodes uint64) bool {
+	if tc.stop.Load() || tc.reachedNodesLimit(nodes) {
 		return true
 	}
 
-	if tc.limits.hardLimit == -1 { // Avoid stop when using Infinite/depth strategy
+	if tc.pondering.Load() || tc.limits.hardLimit == -1 { // Avoid stop when using Infinite/depth strategy
 		return false
 	}
 
 	// If we reached hard limit, stop inmediately
-	if tc.elapsed() >= tc.limits.hardLimit {
-		tc.stop = true
+	if tc.usedTime() >= tc.limits.hardLimit {
+		tc.stop.Store(true)
 		return true
 	}
 
-	return tc.stop
+	return false
+}
+
+// reachedNodesLimit returns true if the nodes searched reached the nodes limit, if any
+func (tc *TimeControl) reachedNodesLimit(nodes uint64) bool {
+	if tc.limits.nodes == 0 || nodes < tc.limits.nodes {
+		return false
+	}
+
+	tc.stop.Store(true)
+	return true
+}
+
+// limitNodes caps the nodes allowed in the search, regardless of the strategy used
+func (tc *TimeControl) limitNodes(nodes uint64) {
+	if tc.limits.nodes == 0 || nodes < tc.limits.nodes {
+		tc.limits.nodes = nodes
+	}
+}
+
+// mateSearchDone returns if the search for a mate is over. Either a mate within the moves limit was found, or
+// the search got deep enough without finding it. Depth is doubled to make up for the reductions in the search
+func (tc *TimeControl) mateSearchDone(score int, depth int) (done bool, found bool) {
+	if tc.strategy != MateStrategy {
+		return false, false
+	}
+
+	maxPly := 2*tc.limits.mate - 1
+	if score > 0 && MateScore-score <= maxPly {
+		return true, true
+	}
+	return depth >= 2*maxPly, false
 }
 
 // shouldStopSearch returns whenever the search should stop
 func (tc *TimeControl) shouldStopSearch(estimatedNextIterationTime int) bool {
-	if tc.strategy != TimeLeftStrategy {
-		return false // Infinite/depth strategy
+	if tc.strategy != TimeLeftStrategy || tc.pondering.Load() {
+		return false // Infinite/depth strategy or pondering
 	}
 
-	if tc.elapsed() >= tc.limits.softLimit || tc.elapsed()+estimatedNextIterationTime >= tc.limits.hardLimit {
-		tc.stop = true
+	if tc.usedTime() >= tc.limits.softLimit || tc.usedTime()+estimatedNextIterationTime >= tc.limits.hardLimit {
+		tc.stop.Store(true)
 		return true
 	}
 
-	return tc.stop
+	return tc.stop.Load()
+}
+
+// String returns the time limits of the search. Limits are not used (and might change on a ponderhit) while pondering
+func (tc *TimeControl) String() string {
+	if tc.pondering.Load() {
+		return "strategy " + strategyNames[tc.strategy] + " pondering elapsed " + strconv.Itoa(tc.elapsed())
+	}
+	return "strategy " + strategyNames[tc.strategy] + " soft limit " + strconv.Itoa(tc.limits.softLimit) +
+		" hard limit " + strconv.Itoa(tc.limits.hardLimit) + " elapsed " + strconv.Itoa(tc.usedTime())
 }
 
 // updateTime updates the search time by the factor
 func (tc *TimeControl) updateTime(factor float64) {
+	if tc.pondering.Load() {
+		return
+	}
 	newSoft := max(tc.limits.minimalTime, int(float64(tc.limits.softLimit)*factor))
 	tc.limits.softLimit = min(newSoft, tc.limits.hardLimit*4/5) // cap at 80%
 }
 
 // TimeStrategy returns the search strategy and the clock for a search
-func TimeStrategy(params []string, depth int, wtime int, btime int, winc int, binc int, movetime int, movesToGo int) (int, Clock) {
+func TimeStrategy(params []string, depth int, wtime int, btime int, winc int, binc int, movetime int, movesToGo int, nodes int, mate int) (int, Clock) {
 	if movetime != -1 {
 		movetime, _ = strconv.Atoi(params[movetime+1])
-		return MoveTimeStrategy, Clock{0, 0, 0, 0, movetime, 0}
+		return MoveTimeStrategy, Clock{0, 0, 0, 0, movetime, 0, 0, 0}
 	}
 	if wtime != -1 || btime != -1 {
 		wtime, _ = strconv.Atoi(params[wtime+1])
@@ -155,12 +243,20 @@ func TimeStrategy(params []string, depth int, wtime int, btime int, winc int, bi
 		if movesToGo != -1 {
 			movesToGo, _ = strconv.Atoi(params[movesToGo+1])
 		}
-		return TimeLeftStrategy, Clock{wtime, btime, winc, binc, 0, movesToGo}
+		return TimeLeftStrategy, Clock{wtime, btime, winc, binc, 0, movesToGo, 0, 0}
+	}
+	if nodes != -1 {
+		nodes, _ = strconv.Atoi(params[nodes+1])
+		return NodesStrategy, Clock{nodes: max(nodes, 1)}
+	}
+	if mate != -1 {
+		mate, _ = strconv.Atoi(params[mate+1])
+		return MateStrategy, Clock{mate: max(mate, 1)}
 	}
 	if depth != -1 {
-		return DepthStrategy, Clock{0, 0, 0, 0, 0, 0}
+		return DepthStrategy, Clock{}
 	}
-	return InfiniteStrategy, Clock{0, 0, 0, 0, 0, 0}
+	return InfiniteStrategy, Clock{}
 }
 
 // estimatedMovesToGo returns the an approximate moves to go for a given move number
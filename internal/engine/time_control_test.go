@@ -40,3 +40,73 @@ func TestCalculateSearchTimeWithMoveTimeStrategy(t *testing.T) {
 		t.Errorf("Expected: %v, got: %v", expected, got)
 	}
 }
+
+func TestShouldNotStopWhilePondering(t *testing.T) {
+	tc := TimeControl{}
+
+	tc.Initialize(MoveTimeStrategy, 1, 1, Clock{moveTime: 0})
+	tc.Ponder()
+
+	expected := false
+	got := tc.shouldStop(0)
+
+	if expected != got {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
+
+func TestShouldStopAfterPonderHit(t *testing.T) {
+	tc := TimeControl{}
+
+	tc.Initialize(MoveTimeStrategy, 1, 1, Clock{moveTime: 0})
+	tc.Ponder()
+	tc.PonderHit()
+
+	expected := true
+	got := tc.shouldStop(0)
+
+	if expected != got {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
+
+func TestShouldStopWhenReachedNodesLimit(t *testing.T) {
+	tc := TimeControl{}
+
+	tc.Initialize(NodesStrategy, 1, 1, Clock{nodes: 1000})
+
+	expected := true
+	got := tc.shouldStop(1000)
+
+	if expected != got {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
+
+func TestMateSearchDoneWhenMateFound(t *testing.T) {
+	tc := TimeControl{}
+
+	tc.Initialize(MateStrategy, 1, 1, Clock{mate: 2})
+
+	expected := true
+	done, got := tc.mateSearchDone(MateScore-3, 3)
+
+	if !done || expected != got {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
+
+func TestSearchWithMateStrategyFindsMate(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString("6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1") // Back rank mate
+	s := NewSearch()
+	s.TimeControl.Initialize(MateStrategy, int(pos.Turn), pos.FullMoveNumber, Clock{mate: 1})
+
+	stdout := make(chan string, 100)
+	score, bestMove := s.IterativeDeepening(pos, MaxSearchDepth, stdout)
+
+	expected := "a1a8"
+	if bestMove != expected || score != MateScore-1 {
+		t.Errorf("Expected: %v, got: %v", expected, bestMove)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"runtime"
 	"runtime/debug"
 	"strconv"
+	"sync/atomic"
 )
 
 const (
@@ -19,18 +20,77 @@ const (
 )
 
 // TTEntry represents a transposition table entry
-// For key it uses only the upper 32 bits of the zobrist hash.
-// Collisions are reduced by using multiple entries per bucket, and when
-// practically minimal if the size of the table is large enough
+// The entry is stored in two words that are read and written atomically, so it can be shared
+// between search threads. The data word packs all the entry fields and the key word stores the
+// zobrist hash xored with the data, so an entry written simultaneously by two threads
+// (torn entry) will not match the hash and will be treated as a miss (lockless hashing)
 type TTEntry struct {
-	key32 uint32 // Upper 32 bits of hash for verification
-	move  Move
-	score int16
-	eval  int16 // Static evaluation
-	depth uint8
-	flag  uint8
-	age   uint8   // Age of the entry
-	_     [3]byte // Alignment
+	key  atomic.Uint64 // Zobrist hash ^ data
+	data atomic.Uint64 // Packed entry data
+}
+
+// load returns the zobrist hash and the data stored in the entry
+func (e *TTEntry) load() (uint64, ttData) {
+	data := e.data.Load()
+	return e.key.Load() ^ data, ttData(data)
+}
+
+// save stores the data in the entry for the zobrist hash passed
+func (e *TTEntry) save(key uint64, data ttData) {
+	e.data.Store(uint64(data))
+	e.key.Store(key ^ uint64(data))
+}
+
+// ttData is the packed data of a transposition table entry
+// bits 0-15 for the move
+// bits 16-31 for the score
+// bits 32-47 for the static evaluation
+// bits 48-55 for the depth
+// bits 56-57 for the flag
+// bits 58-63 for the age
+type ttData uint64
+
+// ttAgeMask is the mask for the age of the entries (6 bits)
+const ttAgeMask = 0b111111
+
+// newTTData returns the packed data of a transposition table entry
+func newTTData(move Move, score int, eval int, depth int, flag uint8, age uint8) ttData {
+	return ttData(uint64(move) |
+		uint64(uint16(int16(score)))<<16 |
+		uint64(uint16(int16(eval)))<<32 |
+		uint64(uint8(depth))<<48 |
+		uint64(flag&0b11)<<56 |
+		uint64(age&ttAgeMask)<<58)
+}
+
+// move returns the move stored in the entry
+func (d ttData) move() Move {
+	return Move(d & 0xFFFF)
+}
+
+// score returns the score stored in the entry
+func (d ttData) score() int {
+	return int(int16(d >> 16))
+}
+
+// eval returns the static evaluation stored in the entry
+func (d ttData) eval() int {
+	return int(int16(d >> 32))
+}
+
+// depth returns the depth of the entry
+func (d ttData) depth() int {
+	return int(uint8(d >> 48))
+}
+
+// flag returns the flag of the entry
+func (d ttData) flag() uint8 {
+	return uint8(d>>56) & 0b11
+}
+
+// age returns the age of the entry
+func (d ttData) age() uint8 {
+	return uint8(d >> 58)
 }
 
 // TTBucket holds multiple entries to reduce collisions
@@ -39,16 +99,28 @@ type TTBucket struct {
 }
 
 // TranspositionTable is a database of previously evaluated positions
+// It can be shared between multiple search threads
 type TranspositionTable struct {
 	buckets []TTBucket
 	size    uint64
 	age     uint8 // Last age/generation of the table
+}
+
+// TTStats are the usage stats of the transposition table by a search thread. Each thread keeps its own stats,
+// so the probes and stores of the threads don't contend on shared counters
+type TTStats struct {
+	stored int64
+	tried  int64
+	hits   int64
+	pruned int64
+}
 
-	// Stats
-	stored int
-	tried  int
-	hits   int
-	pruned int
+// add adds the stats passed
+func (st *TTStats) add(other TTStats) {
+	st.stored += other.stored
+	st.tried += other.tried
+	st.hits += other.hits
+	st.pruned += other.pruned
 }
 
 // NewTranspositionTable returns a pointer to a new TranspositionTable with the passed size
@@ -60,10 +132,6 @@ func NewTranspositionTable(sizeInMb int) *TranspositionTable {
 		buckets: make([]TTBucket, numBuckets),
 		size:    numBuckets,
 		age:     0,
-		stored:  0,
-		tried:   0,
-		hits:    0,
-		pruned:  0,
 	}
 }
 
@@ -84,114 +152,106 @@ func (tt *TranspositionTable) Resize(sizeInMb int) {
 func (tt *TranspositionTable) Clear() {
 	for i := range tt.buckets {
 		for j := range tt.buckets[i].entries {
-			tt.buckets[i].entries[j].key32 = 0
-			tt.buckets[i].entries[j].move = NoMove
-			tt.buckets[i].entries[j].score = 0
-			tt.buckets[i].entries[j].eval = 0
-			tt.buckets[i].entries[j].depth = 0
-			tt.buckets[i].entries[j].flag = 0
-			tt.buckets[i].entries[j].age = 0
+			tt.buckets[i].entries[j].key.Store(0)
+			tt.buckets[i].entries[j].data.Store(0)
 		}
 	}
-	tt.stored = 0
-	tt.tried = 0
-	tt.hits = 0
-	tt.pruned = 0
 	tt.age = 0
 }
 
-// newSearch resets the stats for a new search
+// newSearch ages the entries of the previous searches
 func (tt *TranspositionTable) newSearch() {
 	tt.age++
-	tt.tried = 0
-	tt.hits = 0
-	tt.pruned = 0
-	tt.stored = 0
 }
 
-// store stores a new entry in the transposition table
-func (tt *TranspositionTable) store(key uint64, depth int, ply int, flag uint8, score int, eval int, move Move) {
+// store stores a new entry in the transposition table, counting it on the stats passed
+func (tt *TranspositionTable) store(stats *TTStats, key uint64, depth int, ply int, flag uint8, score int, eval int, move Move) {
 	index := key % tt.size
-	key32 := uint32(key >> 32) // Use only upper 32 bits for hash verification
 	bucket := &tt.buckets[index]
 
 	// Look for existing entry with same key or find best slot to replace
 	replaceIdx, replaceDepth := 0, 0
 
 	for i := range BucketSize {
-		entry := &bucket.entries[i]
+		entryKey, entry := bucket.entries[i].load()
 
 		// Always replace entry for exact key match
-		if entry.key32 == key32 {
+		if entryKey == key {
 			replaceIdx = i
 			break
 		}
 
 		// Empty slot
-		if entry.depth == 0 {
+		if entry.depth() == 0 {
 			replaceIdx = i
-			tt.stored++
+			stats.stored++
 			break
 		}
 
 		// Replacement strategy
 		// New entry replaces the entry with lowest depth
-		if replaceDepth == 0 || int(entry.depth) < replaceDepth {
+		if replaceDepth == 0 || entry.depth() < replaceDepth {
 			replaceIdx = i
-			replaceDepth = int(entry.depth)
+			replaceDepth = entry.depth()
 		}
 	}
 
-	bucket.entries[replaceIdx] = TTEntry{
-		key32: key32,
-		move:  move,
-		score: int16(adjustMateScoreForTT(score, ply)),
-		eval:  int16(eval),
-		depth: uint8(depth),
-		flag:  flag,
-		age:   tt.age,
+	bucket.entries[replaceIdx].save(key, newTTData(move, adjustMateScoreForTT(score, ply), eval, depth, flag, tt.age))
+}
+
+// hashMove returns the move stored for the key passed, without updating the stats of the table
+func (tt *TranspositionTable) hashMove(key uint64) Move {
+	bucket := &tt.buckets[key%tt.size]
+
+	for i := range BucketSize {
+		if entryKey, entry := bucket.entries[i].load(); entryKey == key {
+			return entry.move()
+		}
 	}
+	return NoMove
 }
 
-// probe tries to find an entry in the transposition table
-func (tt *TranspositionTable) probe(key uint64, depth int, ply int, alpha int, beta int) (int, int, Move, bool) {
-	tt.tried++
+// probe tries to find an entry in the transposition table. The data of the entry found is also returned,
+// to allow the search to check the depth and the bound of the stored score. The probe is counted on the stats passed
+func (tt *TranspositionTable) probe(stats *TTStats, key uint64, depth int, ply int, alpha int, beta int) (int, int, Move, bool, ttData) {
+	stats.tried++
 	index := key % tt.size
-	key32 := uint32(key >> 32)
 	bucket := &tt.buckets[index]
 
 	for i := range BucketSize {
-		entry := &bucket.entries[i]
+		entryKey, entry := bucket.entries[i].load()
 
-		if entry.key32 == key32 {
-			tt.hits++
-			move := entry.move
-			eval := int(entry.eval)
+		if entryKey == key {
+			stats.hits++
+			move := entry.move()
+			eval := entry.eval()
 
-			if entry.depth >= uint8(depth) {
-				score := adjustMateScoreFromTT(int(entry.score), ply)
+			if entry.depth() >= depth {
+				score := adjustMateScoreFromTT(entry.score(), ply)
 
 				// Update age to mark as recently used
-				entry.age = tt.age
+				if entry.age() != tt.age&ttAgeMask {
+					bucket.entries[i].save(key, newTTData(move, entry.score(), eval, entry.depth(), entry.flag(), tt.age))
+				}
 
-				if entry.flag == FlagExact {
-					tt.pruned++
-					return score, eval, move, true
+				if entry.flag() == FlagExact {
+					stats.pruned++
+					return score, eval, move, true, entry
 				}
-				if entry.flag == FlagAlpha && score <= alpha {
-					tt.pruned++
-					return alpha, eval, move, true
+				if entry.flag() == FlagAlpha && score <= alpha {
+					stats.pruned++
+					return alpha, eval, move, true, entry
 				}
-				if entry.flag == FlagBeta && score >= beta {
-					tt.pruned++
-					return beta, eval, move, true
+				if entry.flag() == FlagBeta && score >= beta {
+					stats.pruned++
+					return beta, eval, move, true, entry
 				}
 			}
 			// Found entry but can't use score, return move and eval
-			return 0, eval, move, false
+			return 0, eval, move, false, entry
 		}
 	}
-	return 0, 0, NoMove, false
+	return 0, 0, NoMove, false, 0
 }
 
 // hashfull returns the approximate percentage of the transposition table that is used
@@ -204,7 +264,8 @@ func (tt *TranspositionTable) hashfull() int {
 		bucket := &tt.buckets[i]
 		for j := range BucketSize {
 			// Check if entry is occupied (depth > 0)
-			if tt.age == bucket.entries[j].age && bucket.entries[j].depth > 0 {
+			_, entry := bucket.entries[j].load()
+			if tt.age&ttAgeMask == entry.age() && entry.depth() > 0 {
 				used++
 				break // Only count one entry per bucket
 			}
@@ -214,34 +275,43 @@ func (tt *TranspositionTable) hashfull() int {
 	return 1000 * used / sampleSize
 }
 
-// adjustMateScoreForTT converts mate scores to be ply-independent for storage
+// adjustMateScoreForTT converts mate and tablebase scores to be ply-independent for storage
 func adjustMateScoreForTT(score int, ply int) int {
-	if score >= MateScore-MaxSearchDepth {
+	if score >= TBWinScore-MaxSearchDepth {
 		return score + ply
 	}
-	if score <= -MateScore+MaxSearchDepth {
+	if score <= -TBWinScore+MaxSearchDepth {
 		return score - ply
 	}
 	return score
 }
 
-// adjustMateScoreFromTT converts mate scores from TT back to ply-dependent
+// adjustMateScoreFromTT converts mate and tablebase scores from TT back to ply-dependent
 func adjustMateScoreFromTT(score int, ply int) int {
-	if score >= MateScore-MaxSearchDepth {
+	if score >= TBWinScore-MaxSearchDepth {
 		return score - ply
 	}
-	if score <= -MateScore+MaxSearchDepth {
+	if score <= -TBWinScore+MaxSearchDepth {
 		return score + ply
 	}
 	return score
 }
 
-// Stats returns an string with useful Stats about the transposition table
-func (tt *TranspositionTable) Stats() string {
+// Stats returns an string with useful Stats about the transposition table, with the usage stats passed
+func (tt *TranspositionTable) Stats(stats TTStats) string {
 	return "Hashfull: " + strconv.Itoa(tt.hashfull()) + " Age: " + strconv.Itoa(int(tt.age)) + "\n" +
-		"Stored: " + strconv.Itoa(tt.stored) +
-		" Tried: " + strconv.Itoa(tt.tried) + " Hits: " + strconv.Itoa(tt.hits) +
-		" Pruned: " + strconv.Itoa(tt.pruned)
+		"Stored: " + strconv.FormatInt(stats.stored, 10) +
+		" Tried: " + strconv.FormatInt(stats.tried, 10) + " Hits: " + strconv.FormatInt(stats.hits, 10) +
+		" Pruned: " + strconv.FormatInt(stats.pruned, 10) + "\n" +
+		"Hitrate: " + strconv.FormatFloat(stats.hitRate(), 'f', 2, 64)
+}
+
+// hitRate returns the ratio of probes found in the transposition table
+func (st *TTStats) hitRate() float64 {
+	if st.tried == 0 {
+		return 0
+	}
+	return float64(st.hits) / float64(st.tried)
 }
 
 // PawnHashEntry stores the score of the previously evaluated pawn strucure
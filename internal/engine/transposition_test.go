@@ -42,13 +42,13 @@ func TestStoreEntryWithExactMatch(t *testing.T) {
 	eval := 0
 	move := NoMove
 
-	tt.store(key, depth, ply, flag, score, eval, move)
+	tt.store(&TTStats{}, key, depth, ply, flag, score, eval, move)
 
 	index := key % tt.size
 	bucket := &tt.buckets[index]
 
-	expected := uint32(key >> 32)
-	got := bucket.entries[0].key32
+	expected := key
+	got, _ := bucket.entries[0].load()
 
 	if got != expected {
 		t.Errorf("Expected: %v, got: %v", expected, got)
@@ -66,14 +66,15 @@ func TestReplaceEntryWithExactMatch(t *testing.T) {
 	eval := 0
 	move := NoMove
 
-	tt.store(key, depth, ply, flag, score, eval, move)
-	tt.store(key, depth+3, ply, flag, score, eval, move)
+	tt.store(&TTStats{}, key, depth, ply, flag, score, eval, move)
+	tt.store(&TTStats{}, key, depth+3, ply, flag, score, eval, move)
 
 	index := key % tt.size
 	bucket := &tt.buckets[index]
 
+	_, entry := bucket.entries[0].load()
 	expected := depth + 3
-	got := int(bucket.entries[0].depth)
+	got := entry.depth()
 
 	if got != expected {
 		t.Errorf("Expected: %v, got: %v", expected, got)
@@ -91,8 +92,8 @@ func TestProbeEntryWithExactMatch(t *testing.T) {
 	eval := 0
 	move := NoMove
 
-	tt.store(key, depth, ply, flag, score, eval, move)
-	ttScore, ttEval, ttMove, ttHit := tt.probe(key, depth, ply, MinInt, MaxInt)
+	tt.store(&TTStats{}, key, depth, ply, flag, score, eval, move)
+	ttScore, ttEval, ttMove, ttHit, _ := tt.probe(&TTStats{}, key, depth, ply, MinInt, MaxInt)
 
 	expectedScore := score
 	expectedEval := eval
@@ -124,8 +125,8 @@ func TestProbeEntryInsufficientDepth(t *testing.T) {
 	eval := 0
 	move := Move(55)
 
-	tt.store(key, depth, ply, flag, score, eval, move)
-	ttScore, ttEval, ttMove, ttHit := tt.probe(key, depth+1, ply, MinInt, MaxInt)
+	tt.store(&TTStats{}, key, depth, ply, flag, score, eval, move)
+	ttScore, ttEval, ttMove, ttHit, _ := tt.probe(&TTStats{}, key, depth+1, ply, MinInt, MaxInt)
 
 	expectedScore := 0
 	expectedEval := eval
@@ -145,3 +146,23 @@ func TestProbeEntryInsufficientDepth(t *testing.T) {
 		t.Errorf("Expected: %v, got: %v", expectedHit, ttHit)
 	}
 }
+
+func TestProbeReturnsEntryDepthAndFlag(t *testing.T) {
+	tt := NewTranspositionTable(1)
+
+	key := uint64(10240)
+	depth := 7
+	ply := 3
+	flag := FlagBeta
+	move := Move(55)
+
+	tt.store(&TTStats{}, key, depth, ply, flag, 10, 0, move)
+	_, _, _, _, entry := tt.probe(&TTStats{}, key, depth+1, ply, MinInt, MaxInt)
+
+	if entry.depth() != depth {
+		t.Errorf("Expected: %v, got: %v", depth, entry.depth())
+	}
+	if entry.flag() != flag {
+		t.Errorf("Expected: %v, got: %v", flag, entry.flag())
+	}
+}
@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"math"
+	"strconv"
+)
+
+// WinRateModel estimates the win, draw and loss probabilities of a score based on the game phase. The win rate
+// is a logistic function of the score: win = 1 / (1 + exp((a - score) / b)), and the loss rate is the win rate
+// of the negated score. Both a and b are a linear function of the game phase: a = A[0] + A[1]*phase
+type WinRateModel struct {
+	A [2]float64
+	B [2]float64
+}
+
+// DefaultWinRateModel is the model used for the wdl output. Its values are hand picked estimates, not fitted to
+// any dataset: a score of about 150cp in the endgame is taken as a 50% win rate. They should be replaced by the
+// model fitted with tuner.FitWinRateModel on the tuner dataset
+var DefaultWinRateModel = WinRateModel{
+	A: [2]float64{150, 1.0},
+	B: [2]float64{90, 0.5},
+}
+
+// winRate returns the win rate per mille of the score in centipawns for the game phase
+func (m *WinRateModel) winRate(score int, phase int) int {
+	a := m.A[0] + m.A[1]*float64(phase)
+	b := m.B[0] + m.B[1]*float64(phase)
+	return int(math.Round(1000 / (1 + math.Exp((a-float64(score))/b))))
+}
+
+// wdl returns the win, draw and loss probabilities per mille of the score for the game phase
+func (m *WinRateModel) wdl(score int, phase int) (win int, draw int, loss int) {
+	if abs(score) >= MateScore-MaxSearchDepth {
+		if score > 0 {
+			return 1000, 0, 0
+		}
+		return 0, 0, 1000
+	}
+
+	win = m.winRate(score, phase)
+	loss = m.winRate(-score, phase)
+	return win, 1000 - win - loss, loss
+}
+
+// convertWDL returns the wdl string of the score for the uci info output
+func convertWDL(score int, phase int) string {
+	win, draw, loss := DefaultWinRateModel.wdl(score, phase)
+	return "wdl " + strconv.Itoa(win) + " " + strconv.Itoa(draw) + " " + strconv.Itoa(loss)
+}
+
+// gamePhase returns the game phase of the position, from 62 at the start of the game to 0 when only pawns are left
+func (pos *Position) gamePhase() (phase int) {
+	phaseInc := [6]int{0, 9, 5, 3, 3, 0}
+	for p, bb := range pos.Pieces {
+		phase += bb.count() * phaseInc[p%6]
+	}
+	return min(phase, 62)
+}
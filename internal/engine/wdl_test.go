@@ -0,0 +1,46 @@
+package engine
+
+import "testing"
+
+func TestWDLAddsUpToOneThousand(t *testing.T) {
+	for _, score := range []int{-500, -100, 0, 35, 250} {
+		win, draw, loss := DefaultWinRateModel.wdl(score, 30)
+
+		expected := 1000
+		got := win + draw + loss
+
+		if expected != got {
+			t.Errorf("Expected: %v, got: %v", expected, got)
+		}
+	}
+}
+
+func TestWDLIsSymmetric(t *testing.T) {
+	win, _, loss := DefaultWinRateModel.wdl(120, 40)
+	otherWin, _, otherLoss := DefaultWinRateModel.wdl(-120, 40)
+
+	if win != otherLoss || loss != otherWin {
+		t.Errorf("Expected: %v, got: %v", win, otherLoss)
+	}
+}
+
+func TestWDLOnMateScore(t *testing.T) {
+	expected := "wdl 0 0 1000"
+	got := convertWDL(-MateScore+3, 10)
+
+	if expected != got {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
+
+func TestGamePhase(t *testing.T) {
+	pos := NewPosition()
+	pos.LoadFromFenString("4k3/8/8/8/8/8/4P3/R3K3 w - - 0 1")
+
+	expected := 5
+	got := pos.gamePhase()
+
+	if expected != got {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
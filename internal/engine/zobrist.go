@@ -29,6 +29,8 @@ func init() {
 	for i := range zobristHashKeys.epKey {
 		zobristHashKeys.epKey[i] = uint64(r.Uint64())
 	}
+
+	cuckooKeys, cuckooMoves = generateCuckooTables()
 }
 
 // fullZorbistHash calculates the hash of a given position
@@ -91,7 +91,7 @@ func TestZobristIncrementalUpdateOnUnmakeMove(t *testing.T) {
 				for moveNumber := range ml.moves {
 					if ml.moves[moveNumber].String() == move {
 						pos.MakeMove(&ml.moves[moveNumber])
-						movesMade.insert(ml.moves[moveNumber], &movesMade)
+						movesMade = append(pvLine{ml.moves[moveNumber]}, movesMade...)
 						break
 					}
 				}
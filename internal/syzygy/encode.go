@@ -0,0 +1,322 @@
+package syzygy
+
+import "slices"
+
+// Tables to map the pieces of a position to the index of the position on the table
+var (
+	mapPawns      [64]int     // Maps squares a2-h7 to 47-0, the highest being the square nearest to the edge
+	mapB1H1H7     [64]int     // Maps the squares below the a1-h8 diagonal to 0-27
+	mapA1D1D4     [64]int     // Maps the squares of the a1-d1-d4 triangle to 0-9, with the diagonal squares last
+	mapKK         [10][64]int // Maps the 462 legal positions of two kings, the first one on the a1-d1-d4 triangle
+	binomial      [6][64]uint64
+	leadPawnIdx   [6][64]int // Index of the leading pawns by their number and the square of the leading one
+	leadPawnsSize [6][4]int  // Number of positions of the leading pawns by their number and file
+)
+
+func init() {
+	code := 0
+	for sq := range 64 {
+		if offDiagonal(sq) < 0 {
+			mapB1H1H7[sq] = code
+			code++
+		}
+	}
+
+	code = 0
+	diagonal := []int{}
+	for sq := 0; sq <= 27; sq++ {
+		if offDiagonal(sq) < 0 && sq%8 <= 3 {
+			mapA1D1D4[sq] = code
+			code++
+		} else if offDiagonal(sq) == 0 && sq%8 <= 3 {
+			diagonal = append(diagonal, sq)
+		}
+	}
+	for _, sq := range diagonal {
+		mapA1D1D4[sq] = code
+		code++
+	}
+
+	// If the first king is on the diagonal, the other one can't be above it. Positions with both kings on the
+	// diagonal are encoded last
+	code = 0
+	type kingPair struct{ idx, sq int }
+	bothOnDiagonal := []kingPair{}
+	for idx := range 10 {
+		for sq1 := 0; sq1 <= 27; sq1++ {
+			if mapA1D1D4[sq1] != idx || (idx == 0 && sq1 != 1) {
+				continue
+			}
+			for sq2 := range 64 {
+				switch {
+				case abs(sq1/8-sq2/8) <= 1 && abs(sq1%8-sq2%8) <= 1:
+					continue
+				case offDiagonal(sq1) == 0 && offDiagonal(sq2) > 0:
+					continue
+				case offDiagonal(sq1) == 0 && offDiagonal(sq2) == 0:
+					bothOnDiagonal = append(bothOnDiagonal, kingPair{idx, sq2})
+				default:
+					mapKK[idx][sq2] = code
+					code++
+				}
+			}
+		}
+	}
+	for _, pair := range bothOnDiagonal {
+		mapKK[pair.idx][pair.sq] = code
+		code++
+	}
+
+	binomial[0][0] = 1
+	for n := 1; n < 64; n++ {
+		for k := 0; k < 6 && k <= n; k++ {
+			if k > 0 {
+				binomial[k][n] += binomial[k-1][n-1]
+			}
+			if k < n {
+				binomial[k][n] += binomial[k][n-1]
+			}
+		}
+	}
+
+	availableSquares := 47
+	for leadPawns := 1; leadPawns <= 5; leadPawns++ {
+		for file := range 4 {
+			idx := 0
+			for rank := 1; rank <= 6; rank++ {
+				sq := rank*8 + file
+				if leadPawns == 1 {
+					mapPawns[sq] = availableSquares
+					mapPawns[flipFile(sq)] = availableSquares - 1
+					availableSquares -= 2
+				}
+				leadPawnIdx[leadPawns][sq] = idx
+				idx += int(binomial[leadPawns-1][mapPawns[sq]])
+			}
+			leadPawnsSize[leadPawns][file] = idx
+		}
+	}
+}
+
+// offDiagonal returns the distance of the square to the a1-h8 diagonal, positive above it and negative below
+func offDiagonal(sq int) int {
+	return sq/8 - sq%8
+}
+
+// flipFile returns the square mirrored horizontally
+func flipFile(sq int) int {
+	return sq ^ 7
+}
+
+// flipRank returns the square mirrored vertically
+func flipRank(sq int) int {
+	return sq ^ 56
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// probeTable returns the value of the position on the wdl or dtz table. The wdl passed is only used by dtz tables
+func (tb *Tablebases) probeTable(pos *Position, dtz bool, wdl WDL) (value int, result ProbeResult) {
+	key := materialKey(&pos.Board)
+	if key == "KvK" {
+		return 0, Found
+	}
+
+	t, found := tb.tables[key]
+	if !found {
+		return 0, Failed
+	}
+	tf := &t.wdl
+	if dtz {
+		tf = &t.dtz
+	}
+	if !tf.load(t) {
+		return 0, Failed
+	}
+
+	// Corrupted files might lead to out of range offsets
+	defer func() {
+		if recover() != nil {
+			value, result = 0, Failed
+		}
+	}()
+
+	idx, d, file, result := tf.encode(t, pos)
+	if result != Found {
+		return 0, result
+	}
+	return tf.mapScore(t, file, d.decompress(tf.data, idx), wdl), Found
+}
+
+// encode returns the index of the position on the file, with the table and the file of the leading pawn where
+// it's stored
+func (tf *tableFile) encode(t *table, pos *Position) (uint64, *pairsData, int, ProbeResult) {
+	// Tables are stored with the stronger side as white, and symmetric tables only with white to move, so the
+	// colors and squares of the position are flipped when needed
+	flip := materialKey(&pos.Board) != t.key || (t.key == t.key2 && pos.BlackToMove)
+	flipColor, flipSquares, stm := Piece(0), 0, 0
+	if flip {
+		flipColor, flipSquares = 8, 56
+	}
+	if flip != pos.BlackToMove {
+		stm = 1
+	}
+
+	var squares [MaxPieces]int
+	var pieces [MaxPieces]Piece
+	size, leadPawns, file := 0, 0, 0
+
+	// The leading pawns are the pawns of the color of the first piece of the tables. The leading one is the one
+	// nearest to the edge, and with the lowest rank among the ones on the same file
+	if t.hasPawns {
+		leadPawn := tf.items[0][0].pieces[0] ^ flipColor
+		for sq, piece := range pos.Board {
+			if piece == leadPawn {
+				squares[size] = sq ^ flipSquares
+				pieces[size] = leadPawn
+				size++
+			}
+		}
+		leadPawns = size
+
+		lead := 0
+		for i := 1; i < leadPawns; i++ {
+			if mapPawns[squares[i]] > mapPawns[squares[lead]] {
+				lead = i
+			}
+		}
+		squares[0], squares[lead] = squares[lead], squares[0]
+		file = min(squares[0]%8, 7-squares[0]%8)
+	}
+
+	if tf.isDTZ && !tf.hasSideToMove(t, stm, file) {
+		return 0, nil, 0, OtherSideToMove
+	}
+
+	for sq, piece := range pos.Board {
+		if piece == NoPiece || (t.hasPawns && piece == tf.items[0][0].pieces[0]^flipColor) {
+			continue
+		}
+		squares[size] = sq ^ flipSquares
+		pieces[size] = piece ^ flipColor
+		size++
+	}
+
+	// Sort the pieces in the order of the table
+	d := tf.get(t, stm, file)
+	for i := leadPawns; i < size-1; i++ {
+		for j := i + 1; j < size; j++ {
+			if d.pieces[i] == pieces[j] {
+				pieces[i], pieces[j] = pieces[j], pieces[i]
+				squares[i], squares[j] = squares[j], squares[i]
+				break
+			}
+		}
+	}
+
+	// Mirror the position to get the leading piece on the a1-d1-d4 triangle, or the leading pawn on files a-d
+	if squares[0]%8 > 3 {
+		for i := range size {
+			squares[i] = flipFile(squares[i])
+		}
+	}
+
+	idx := uint64(0)
+	if t.hasPawns {
+		idx = uint64(leadPawnIdx[leadPawns][squares[0]])
+		slices.SortStableFunc(squares[1:leadPawns], func(a, b int) int { return mapPawns[a] - mapPawns[b] })
+		for i := 1; i < leadPawns; i++ {
+			idx += binomial[i][mapPawns[squares[i]]]
+		}
+	} else {
+		idx = encodeLeadingPieces(t, d, squares[:size])
+	}
+
+	// Encode the remaining groups. The squares of each group are mapped down by the pieces of the previous groups
+	// on lower squares, and the squares of the pawns of the other color by the 8 squares of the first rank
+	idx *= d.groupIdx[0]
+	first := d.groupLen[0]
+	remainingPawns := t.hasPawns && t.pawnCount[1] > 0
+	for next := 1; d.groupLen[next] != 0; next++ {
+		group := squares[first : first+d.groupLen[next]]
+		slices.Sort(group)
+
+		n := uint64(0)
+		for i, sq := range group {
+			adjust := 0
+			for _, previous := range squares[:first] {
+				if sq > previous {
+					adjust++
+				}
+			}
+			if remainingPawns {
+				adjust += 8
+			}
+			n += binomial[i+1][sq-adjust]
+		}
+
+		remainingPawns = false
+		idx += n * d.groupIdx[next]
+		first += d.groupLen[next]
+	}
+
+	return idx, d, file, Found
+}
+
+// encodeLeadingPieces returns the index of the leading group of a pawnless position: the first 3 pieces when there
+// are unique pieces, or the kings otherwise. The squares are mirrored to have the leading piece on the a1-d1-d4
+// triangle, and the first piece not on the a1-h8 diagonal below it
+func encodeLeadingPieces(t *table, d *pairsData, squares []int) uint64 {
+	if squares[0]/8 > 3 {
+		for i := range squares {
+			squares[i] = flipRank(squares[i])
+		}
+	}
+
+	for i := range d.groupLen[0] {
+		if offDiagonal(squares[i]) == 0 {
+			continue
+		}
+		if offDiagonal(squares[i]) > 0 {
+			for j := i; j < len(squares); j++ {
+				squares[j] = (squares[j]>>3 | squares[j]<<3) & 63
+			}
+		}
+		break
+	}
+
+	if !t.hasUniquePieces {
+		return uint64(mapKK[mapA1D1D4[squares[0]]][squares[1]])
+	}
+
+	adjust1 := boolToInt(squares[1] > squares[0])
+	adjust2 := boolToInt(squares[2] > squares[0]) + boolToInt(squares[2] > squares[1])
+
+	switch {
+	case offDiagonal(squares[0]) != 0:
+		return uint64((mapA1D1D4[squares[0]]*63+squares[1]-adjust1)*62 + squares[2] - adjust2)
+	case offDiagonal(squares[1]) != 0:
+		return uint64((6*63+(squares[0]/8)*28+mapB1H1H7[squares[1]])*62 + squares[2] - adjust2)
+	case offDiagonal(squares[2]) != 0:
+		return uint64(6*63*62 + 4*28*62 + (squares[0]/8)*7*28 + (squares[1]/8-adjust1)*28 + mapB1H1H7[squares[2]])
+	default:
+		return uint64(6*63*62 + 4*28*62 + 4*7*28 + (squares[0]/8)*7*6 + (squares[1]/8-adjust1)*6 + squares[2]/8 - adjust2)
+	}
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// hasSideToMove returns if the dtz table stores the positions with the side to move passed
+func (tf *tableFile) hasSideToMove(t *table, stm int, file int) bool {
+	return int(tf.get(t, stm, file).flags&stmFlag) == stm || (t.key == t.key2 && !t.hasPawns)
+}
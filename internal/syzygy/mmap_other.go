@@ -0,0 +1,13 @@
+//go:build !unix
+
+package syzygy
+
+import "os"
+
+// mapFile reads the file passed into memory
+func mapFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+// unmapFile releases the memory of a file read
+func unmapFile(data []byte) {}
@@ -0,0 +1,169 @@
+// Package syzygy probes the Syzygy endgame tablebases. WDL tables store the win/draw/loss value of the positions
+// and DTZ tables the distance to the next zeroing move (a capture or a pawn move). The file format and the
+// indexing scheme are the ones of the tablebase generator by Ronald de Man
+package syzygy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	WDLSuffix = ".rtbw"
+	DTZSuffix = ".rtbz"
+	MaxPieces = 7 // Max pieces of the positions stored in the tables
+)
+
+// Piece is the piece code used by the tables. White pieces go from 1 (pawn) to 6 (king), and black
+// pieces have the same codes plus 8
+type Piece uint8
+
+const (
+	NoPiece Piece = iota
+	WhitePawn
+	WhiteKnight
+	WhiteBishop
+	WhiteRook
+	WhiteQueen
+	WhiteKing
+)
+
+const (
+	BlackPawn Piece = iota + 9
+	BlackKnight
+	BlackBishop
+	BlackRook
+	BlackQueen
+	BlackKing
+)
+
+// pieceChars are the letters of the pieces on the table names, indexed by the piece code without color
+const pieceChars = " PNBRQK"
+
+// Position is a position to probe. Squares go from a1 (0) to h8 (63)
+type Position struct {
+	Board       [64]Piece
+	BlackToMove bool
+}
+
+// WDL is the value of a position for the side to move
+type WDL int
+
+const (
+	Loss        WDL = -2
+	BlessedLoss WDL = -1 // Loss that is saved by the 50 move rule
+	Draw        WDL = 0
+	CursedWin   WDL = 1 // Win that is spoiled by the 50 move rule
+	Win         WDL = 2
+)
+
+// ProbeResult is the result of probing a table
+type ProbeResult int
+
+const (
+	Failed          ProbeResult = iota // The table is not available or it can't be read
+	Found                              // The value was found in the table
+	OtherSideToMove                    // The dtz table only stores the positions with the other side to move
+)
+
+// Tablebases holds the tables found on the syzygy path. Files are mapped the first time they are probed
+type Tablebases struct {
+	tables    map[string]*table // Tables by the material key of the position with both color arrangements
+	count     int
+	maxPieces int
+}
+
+// Open looks for the tables on the directories of the path passed, separated by the os path list separator
+func Open(path string) (*Tablebases, error) {
+	tb := &Tablebases{tables: map[string]*table{}}
+	wdlFiles, dtzFiles := map[string]string{}, map[string]string{}
+
+	for _, dir := range filepath.SplitList(path) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			if name, found := strings.CutSuffix(entry.Name(), WDLSuffix); found && validTableName(name) {
+				wdlFiles[name] = filepath.Join(dir, entry.Name())
+			}
+			if name, found := strings.CutSuffix(entry.Name(), DTZSuffix); found && validTableName(name) {
+				dtzFiles[name] = filepath.Join(dir, entry.Name())
+			}
+		}
+	}
+
+	for name, wdlPath := range wdlFiles {
+		t := newTable(name, wdlPath, dtzFiles[name])
+		tb.tables[t.key] = t
+		tb.tables[t.key2] = t
+		tb.count++
+		tb.maxPieces = max(tb.maxPieces, t.pieceCount)
+	}
+	return tb, nil
+}
+
+// Count returns the number of wdl tables found
+func (tb *Tablebases) Count() int {
+	return tb.count
+}
+
+// MaxPieces returns the number of pieces of the largest table found
+func (tb *Tablebases) MaxPieces() int {
+	return tb.maxPieces
+}
+
+// Close unmaps all the files of the tables probed
+func (tb *Tablebases) Close() {
+	for key, t := range tb.tables {
+		if key == t.key {
+			t.wdl.close()
+			t.dtz.close()
+		}
+	}
+}
+
+// ProbeWDL returns the value stored in the wdl table for the position. The tables don't store the right value
+// for positions where a capture is the best move, so the captures must be probed by the caller
+func (tb *Tablebases) ProbeWDL(pos *Position) (WDL, ProbeResult) {
+	value, result := tb.probeTable(pos, false, Draw)
+	return WDL(value), result
+}
+
+// ProbeDTZ returns the distance to zeroing in plies stored in the dtz table for the position, with the wdl value
+// passed. As the wdl tables, they don't store the right value for positions where a zeroing move is the best move
+func (tb *Tablebases) ProbeDTZ(pos *Position, wdl WDL) (int, ProbeResult) {
+	return tb.probeTable(pos, true, wdl)
+}
+
+// validTableName returns if the name passed is a table name, like KRPvKR
+func validTableName(name string) bool {
+	white, black, found := strings.Cut(name, "v")
+	if !found || !strings.HasPrefix(white, "K") || !strings.HasPrefix(black, "K") || len(white)+len(black) > MaxPieces {
+		return false
+	}
+	return strings.Trim(white[1:]+black[1:], pieceChars[1:6]) == ""
+}
+
+// materialKey returns the name of the table of the pieces passed, with the white pieces first
+func materialKey(board *[64]Piece) string {
+	var counts [16]int
+	for _, piece := range board {
+		counts[piece]++
+	}
+
+	key := make([]byte, 0, MaxPieces+1)
+	for _, color := range []Piece{0, 8} {
+		if color == 8 {
+			key = append(key, 'v')
+		}
+		for piece := WhiteKing; piece >= WhitePawn; piece-- {
+			for range counts[piece+color] {
+				key = append(key, pieceChars[piece])
+			}
+		}
+	}
+	return string(key)
+}
@@ -0,0 +1,452 @@
+package syzygy
+
+import (
+	"os"
+	"slices"
+	"testing"
+)
+
+// symmetries returns the positions equivalent to the passed by mirroring the board, when there are no pawns
+func (p kqk) symmetries() []kqk {
+	transforms := []func(int) int{
+		func(sq int) int { return sq },
+		flipFile,
+		flipRank,
+		func(sq int) int { return flipFile(flipRank(sq)) },
+	}
+	positions := []kqk{}
+	for _, transpose := range []bool{false, true} {
+		for _, transform := range transforms {
+			mirror := func(sq int) int {
+				if transpose {
+					sq = (sq>>3 | sq<<3) & 63
+				}
+				return transform(sq)
+			}
+			positions = append(positions, kqk{mirror(p.whiteKing), mirror(p.queen), mirror(p.blackKing)})
+		}
+	}
+	return positions
+}
+
+// swapColors returns the position with the colors of the pieces swapped and the board mirrored vertically
+func swapColors(pos *Position) *Position {
+	swapped := &Position{BlackToMove: !pos.BlackToMove}
+	for sq, piece := range pos.Board {
+		if piece != NoPiece {
+			swapped.Board[flipRank(sq)] = piece ^ 8
+		}
+	}
+	return swapped
+}
+
+func TestOpen(t *testing.T) {
+	tb, err := Open(testdataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tb.Close()
+
+	expected := [2]int{2, 3}
+	got := [2]int{tb.Count(), tb.MaxPieces()}
+
+	if got != expected {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
+
+func TestOpenMissingDirectory(t *testing.T) {
+	_, err := Open(testdataPath + "/missing")
+
+	if err == nil {
+		t.Errorf("Expected: an error, got: %v", err)
+	}
+}
+
+func TestValidTableName(t *testing.T) {
+	names := map[string]bool{"KQvK": true, "KRPvKR": true, "KBNvK": true, "KQvKX": false, "QKvK": false, "KQK": false}
+
+	for name, expected := range names {
+		got := validTableName(name)
+
+		if got != expected {
+			t.Errorf("Expected: %v, got: %v for %v", expected, got, name)
+		}
+	}
+}
+
+func TestMaterialKey(t *testing.T) {
+	pos := Position{}
+	pos.Board[0], pos.Board[1], pos.Board[8], pos.Board[63], pos.Board[62] = WhiteKing, WhitePawn, WhiteRook, BlackKing, BlackQueen
+
+	expected := "KRPvKQ"
+	got := materialKey(&pos.Board)
+
+	if got != expected {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
+
+func TestKingsEncoding(t *testing.T) {
+	expected := 462
+	got := 0
+	for idx := range 10 {
+		for sq := range 64 {
+			got = max(got, mapKK[idx][sq]+1)
+		}
+	}
+
+	if got != expected {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
+
+func TestPawnlessEncodingIsUniqueForEachSymmetry(t *testing.T) {
+	tf := newTestTableFile("KQvK", false, []Piece{WhiteQueen, WhiteKing, BlackKing}, [2]byte{})
+	table := newTable("KQvK", "", "")
+	size := tf.items[0][0].groupIdx[1]
+	positions := map[uint64]kqk{}
+
+	for _, p := range allKQK() {
+		if !p.legal(false) {
+			continue
+		}
+		canonical := p
+		for _, symmetric := range p.symmetries() {
+			if symmetric.whiteKing*4096+symmetric.queen*64+symmetric.blackKing < canonical.whiteKing*4096+canonical.queen*64+canonical.blackKing {
+				canonical = symmetric
+			}
+		}
+
+		idx, _, _, _ := tf.encode(table, p.position(false))
+		if idx >= size {
+			t.Fatalf("Expected: index lower than %v, got: %v", size, idx)
+		}
+		if other, found := positions[idx]; found && other != canonical {
+			t.Fatalf("Expected: %v, got: %v at index %v", other, canonical, idx)
+		}
+		positions[idx] = canonical
+	}
+}
+
+func TestPawnEncodingIsUniqueForEachSymmetry(t *testing.T) {
+	tf := newTestTableFile("KPvK", false, []Piece{WhitePawn, WhiteKing, BlackKing}, [2]byte{})
+	table := newTable("KPvK", "", "")
+	positions := [4]map[uint64][3]int{{}, {}, {}, {}}
+
+	for pawn := 8; pawn < 56; pawn++ {
+		for whiteKing := range 64 {
+			for blackKing := range 64 {
+				if whiteKing == pawn || blackKing == pawn || whiteKing == blackKing || adjacent(whiteKing, blackKing) {
+					continue
+				}
+				pos := &Position{}
+				pos.Board[pawn], pos.Board[whiteKing], pos.Board[blackKing] = WhitePawn, WhiteKing, BlackKing
+				canonical := [3]int{pawn, whiteKing, blackKing}
+				if pawn%8 > 3 {
+					canonical = [3]int{flipFile(pawn), flipFile(whiteKing), flipFile(blackKing)}
+				}
+
+				idx, d, file, _ := tf.encode(table, pos)
+				if size := d.groupIdx[3]; idx >= size || file != canonical[0]%8 {
+					t.Fatalf("Expected: index lower than %v on file %v, got: %v on file %v", size, canonical[0]%8, idx, file)
+				}
+				if other, found := positions[file][idx]; found && other != canonical {
+					t.Fatalf("Expected: %v, got: %v at index %v", other, canonical, idx)
+				}
+				positions[file][idx] = canonical
+			}
+		}
+	}
+}
+
+func TestIndexTablesMatchTheReferenceImplementation(t *testing.T) {
+	// Squares of the a1-d1-d4 triangle of the reference prober, with the diagonal squares last
+	triangle := map[int]int{0: 6, 1: 0, 2: 1, 3: 2, 9: 7, 10: 3, 11: 4, 18: 8, 19: 5, 27: 9}
+	for sq, expected := range triangle {
+		if got := mapA1D1D4[sq]; got != expected {
+			t.Errorf("Expected: %v, got: %v at square %v", expected, got, sq)
+		}
+	}
+
+	// Pawns are mapped from a2 (47) and h2 (46), file by file up to d7 (1) and e7 (0)
+	pawns := map[int]int{8: 47, 15: 46, 16: 45, 48: 37, 55: 36, 9: 35, 51: 1, 52: 0}
+	for sq, expected := range pawns {
+		if got := mapPawns[sq]; got != expected {
+			t.Errorf("Expected: %v, got: %v at square %v", expected, got, sq)
+		}
+	}
+
+	kings := 0
+	for idx := range mapKK {
+		kings = max(kings, slices.Max(mapKK[idx][:])+1)
+	}
+	got := [4]int{kings, leadPawnsSize[1][0], leadPawnsSize[1][3], leadPawnsSize[2][0]}
+	expected := [4]int{462, 6, 6, 47 + 45 + 43 + 41 + 39 + 37}
+	if got != expected {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
+
+func TestTableSizesMatchTheReferenceImplementation(t *testing.T) {
+	testCases := []struct {
+		name     string
+		pieces   []Piece
+		order    [2]int
+		expected uint64
+	}{
+		{"KQvK", []Piece{WhiteQueen, WhiteKing, BlackKing}, [2]int{0, 0xF}, 31332},
+		{"KRRvK", []Piece{WhiteKing, BlackKing, WhiteRook, WhiteRook}, [2]int{0, 0xF}, 462 * 1891},
+		{"KQvKR", []Piece{WhiteQueen, WhiteKing, BlackKing, BlackRook}, [2]int{0, 0xF}, 31332 * 61},
+		{"KPvK", []Piece{WhitePawn, WhiteKing, BlackKing}, [2]int{0, 0xF}, 6 * 63 * 62},
+		{"KRvKP", []Piece{BlackPawn, WhiteKing, WhiteRook, BlackKing}, [2]int{0, 0xF}, 6 * 63 * 62 * 61},
+		{"KRPvKR", []Piece{WhitePawn, WhiteKing, WhiteRook, BlackKing, BlackRook}, [2]int{0, 0xF}, 6 * 63 * 62 * 61 * 60},
+		{"KPPvKP", []Piece{BlackPawn, WhitePawn, WhitePawn, WhiteKing, BlackKing}, [2]int{0, 1}, 6 * 1081 * 61 * 60},
+	}
+
+	for _, tc := range testCases {
+		d := &pairsData{}
+		copy(d.pieces[:], tc.pieces)
+		d.setGroups(newTable(tc.name, "", ""), tc.order, 0)
+
+		got := d.groupIdx[slices.Index(d.groupLen[:], 0)]
+
+		if got != tc.expected {
+			t.Errorf("Expected: %v, got: %v at %v", tc.expected, got, tc.name)
+		}
+	}
+}
+
+func TestProbeKPvK(t *testing.T) {
+	tb, err := Open(testdataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tb.Close()
+
+	testCases := []struct {
+		name     string
+		board    map[int]Piece
+		black    bool
+		expected WDL
+		dtz      int
+	}{
+		{"Outside the square", map[int]Piece{32: WhitePawn, 4: WhiteKing, 0: BlackKing}, false, Win, 1},
+		{"Black pawn outside the square", map[int]Piece{24: BlackPawn, 60: BlackKing, 56: WhiteKing}, true, Win, 1},
+		{"King on the sixth rank in front of the pawn", map[int]Piece{36: WhitePawn, 44: WhiteKing, 60: BlackKing}, true, Loss, 0},
+		{"Rook pawn with the king on the corner", map[int]Piece{32: WhitePawn, 40: WhiteKing, 56: BlackKing}, false, Draw, 0},
+		{"Stalemate", map[int]Piece{48: WhitePawn, 41: WhiteKing, 56: BlackKing}, true, Draw, 0},
+	}
+
+	for _, tc := range testCases {
+		pos := &Position{BlackToMove: tc.black}
+		for sq, piece := range tc.board {
+			pos.Board[sq] = piece
+		}
+
+		wdl, result := tb.ProbeWDL(pos)
+		if wdl != tc.expected || result != Found {
+			t.Errorf("Expected: %v, got: %v (%v) at %v", tc.expected, wdl, result, tc.name)
+		}
+		if tc.dtz != 0 {
+			if dtz, _ := tb.ProbeDTZ(pos, wdl); dtz != tc.dtz {
+				t.Errorf("Expected: %v, got: %v at %v", tc.dtz, dtz, tc.name)
+			}
+		}
+	}
+}
+
+func TestProbeKQvK(t *testing.T) {
+	tb, err := Open(testdataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tb.Close()
+
+	// Probe all the positions, and check that the values are consistent with the moves of each position
+	index := func(p kqk) int { return p.whiteKing*4096 + p.queen*64 + p.blackKing }
+	dtz := make([]int, 64*64*64)
+	for _, p := range allKQK() {
+		if !p.legal(false) {
+			continue
+		}
+		wdl, result := tb.ProbeWDL(p.position(false))
+		if wdl != Win || result != Found {
+			t.Fatalf("Expected: %v, got: %v (%v) at %v", Win, wdl, result, p)
+		}
+		dtz[index(p)], result = tb.ProbeDTZ(p.position(false), Win)
+		if result != Found {
+			t.Fatalf("Expected: %v, got: %v at %v", Found, result, p)
+		}
+	}
+
+	// A lost position with black to move is a checkmate or a position where all the moves lead to won positions
+	pliesToMate := make([]int, 64*64*64)
+	for _, p := range allKQK() {
+		if !p.legal(true) {
+			continue
+		}
+		moves, plies := p.blackMoves(), 0
+		if len(moves) == 0 && !p.queenAttacks(p.blackKing) {
+			plies = -1
+		}
+		for _, next := range moves {
+			if next.queen < 0 {
+				plies = -1
+				break
+			}
+			plies = max(plies, dtz[index(next)]+1)
+		}
+		pliesToMate[index(p)] = plies
+
+		expected := Draw
+		if pliesToMate[index(p)] >= 0 {
+			expected = Loss
+		}
+		got, _ := tb.ProbeWDL(p.position(true))
+
+		if got != expected {
+			t.Fatalf("Expected: %v, got: %v at %v", expected, got, p)
+		}
+	}
+
+	for _, p := range allKQK() {
+		if !p.legal(false) {
+			continue
+		}
+		expected := -1
+		for _, next := range p.whiteMoves() {
+			if plies := pliesToMate[index(next)]; plies >= 0 && (expected == -1 || plies+1 < expected) {
+				expected = plies + 1
+			}
+		}
+		got := dtz[index(p)]
+
+		if got != expected {
+			t.Fatalf("Expected: %v, got: %v at %v", expected, got, p)
+		}
+	}
+}
+
+func TestProbeWithSwappedColors(t *testing.T) {
+	tb, err := Open(testdataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tb.Close()
+
+	for _, p := range allKQK() {
+		if !p.legal(true) {
+			continue
+		}
+		expected, _ := tb.ProbeWDL(p.position(true))
+		got, result := tb.ProbeWDL(swapColors(p.position(true)))
+
+		if got != expected || result != Found {
+			t.Fatalf("Expected: %v, got: %v (%v) at %v", expected, got, result, p)
+		}
+	}
+}
+
+func TestProbeDTZWithTheOtherSideToMove(t *testing.T) {
+	tb, err := Open(testdataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tb.Close()
+
+	// Kb6 Qc2 vs Ka8, the dtz table only stores the positions with white to move
+	p := kqk{whiteKing: 41, queen: 10, blackKing: 56}
+	_, result := tb.ProbeDTZ(p.position(true), Loss)
+
+	expected := OtherSideToMove
+	if result != expected {
+		t.Errorf("Expected: %v, got: %v", expected, result)
+	}
+}
+
+func TestProbeMissingTables(t *testing.T) {
+	tb, err := Open(testdataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tb.Close()
+
+	pos := &Position{}
+	pos.Board[4], pos.Board[60], pos.Board[0] = WhiteKing, BlackKing, WhiteRook
+	_, result := tb.ProbeWDL(pos)
+
+	if result != Failed {
+		t.Errorf("Expected: %v, got: %v", Failed, result)
+	}
+
+	pos.Board[0] = NoPiece
+	wdl, result := tb.ProbeWDL(pos)
+
+	if wdl != Draw || result != Found {
+		t.Errorf("Expected: %v, got: %v (%v)", Draw, wdl, result)
+	}
+}
+
+func TestProbeMateInOne(t *testing.T) {
+	tb, err := Open(testdataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tb.Close()
+
+	// Kb6 Qc2 vs Ka8, Qc8 mates
+	p := kqk{whiteKing: 41, queen: 10, blackKing: 56}
+	got, _ := tb.ProbeDTZ(p.position(false), Win)
+
+	expected := 1
+	if got != expected {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
+
+func TestProbeSyzygyTables(t *testing.T) {
+	// The tables of the testdata directory are generated by the tests, so the prober is also checked against the
+	// published tables when they are available
+	path := os.Getenv("SYZYGY_PATH")
+	if path == "" {
+		t.Skip("Set SYZYGY_PATH to the directory of the KQvK and KRvK tables")
+	}
+	tb, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tb.Close()
+
+	testCases := []struct {
+		name        string
+		pieces      map[int]Piece
+		blackToMove bool
+		wdl         WDL
+		dtz         int
+	}{
+		{"Kb6 Qc2 vs Ka8, Qc8 mates", map[int]Piece{41: WhiteKing, 10: WhiteQueen, 56: BlackKing}, false, Win, 1},
+		{"Kh1 Qb7 vs Ka8, Kxb7", map[int]Piece{7: WhiteKing, 49: WhiteQueen, 56: BlackKing}, true, Draw, 0},
+		{"Kb6 Rc1 vs Ka8, Rc8 mates", map[int]Piece{41: WhiteKing, 2: WhiteRook, 56: BlackKing}, false, Win, 1},
+		{"Kh1 Rb7 vs Ka8, Kxb7", map[int]Piece{7: WhiteKing, 49: WhiteRook, 56: BlackKing}, true, Draw, 0},
+	}
+
+	for _, tc := range testCases {
+		pos := &Position{BlackToMove: tc.blackToMove}
+		for sq, piece := range tc.pieces {
+			pos.Board[sq] = piece
+		}
+
+		wdl, result := tb.ProbeWDL(pos)
+		if wdl != tc.wdl || result != Found {
+			t.Errorf("%v: Expected: %v, got: %v (%v)", tc.name, tc.wdl, wdl, result)
+		}
+		if tc.wdl != Win {
+			continue
+		}
+		dtz, result := tb.ProbeDTZ(pos, tc.wdl)
+		if dtz != tc.dtz || result != Found {
+			t.Errorf("%v: Expected: %v, got: %v (%v)", tc.name, tc.dtz, dtz, result)
+		}
+	}
+}
@@ -0,0 +1,489 @@
+package syzygy
+
+import (
+	"encoding/binary"
+	"strings"
+	"sync"
+)
+
+var (
+	wdlMagic = [4]byte{0x71, 0xE8, 0x23, 0x5D}
+	dtzMagic = [4]byte{0xD7, 0x66, 0x0C, 0xA5}
+)
+
+// Flags of the header of the files
+const (
+	splitFlag    = 1 // The wdl file stores both sides to move
+	hasPawnsFlag = 2
+)
+
+// Flags of each table of a file. All of them refer to dtz tables except singleValueFlag
+const (
+	stmFlag         = 1 // Side to move stored in the dtz table
+	mappedFlag      = 2 // Dtz values are mapped
+	winPliesFlag    = 4 // Dtz values of wins are stored in plies instead of moves
+	lossPliesFlag   = 8 // Dtz values of losses are stored in plies instead of moves
+	wideFlag        = 16
+	singleValueFlag = 128 // All the positions have the same value
+)
+
+// table is an endgame table, like KRvK, with its wdl and dtz files
+type table struct {
+	key             string // Material key with the pieces of the stronger side as white
+	key2            string // Material key with the colors swapped
+	pieceCount      int
+	hasPawns        bool
+	hasUniquePieces bool   // If there is a piece (not a king) that is the only one of its type and color
+	pawnCount       [2]int // Pawns of the leading color (the one with less pawns) and of the other color
+	wdl             tableFile
+	dtz             tableFile
+}
+
+// newTable returns the table with the name passed and the paths of its files
+func newTable(name string, wdlPath string, dtzPath string) *table {
+	white, black, _ := strings.Cut(name, "v")
+	t := &table{
+		key:        name,
+		key2:       black + "v" + white,
+		pieceCount: len(white) + len(black),
+		hasPawns:   strings.Contains(name, "P"),
+		wdl:        tableFile{path: wdlPath, magic: wdlMagic},
+		dtz:        tableFile{path: dtzPath, magic: dtzMagic, isDTZ: true},
+	}
+
+	for _, piece := range pieceChars[1:6] {
+		if strings.Count(white, string(piece)) == 1 || strings.Count(black, string(piece)) == 1 {
+			t.hasUniquePieces = true
+		}
+	}
+
+	whitePawns, blackPawns := strings.Count(white, "P"), strings.Count(black, "P")
+	t.pawnCount = [2]int{whitePawns, blackPawns}
+	if blackPawns > 0 && (whitePawns == 0 || blackPawns < whitePawns) {
+		t.pawnCount = [2]int{blackPawns, whitePawns}
+	}
+	return t
+}
+
+// tableFile is a wdl or dtz file of a table. Pawnless tables have a single table for each side to move, and tables
+// with pawns have one for each file (a to d) of the leading pawn
+type tableFile struct {
+	path   string
+	magic  [4]byte
+	isDTZ  bool
+	once   sync.Once
+	loaded bool
+	data   []byte
+	sides  int
+	items  [2][4]pairsData // Indexed by side to move and file of the leading pawn
+	dtzMap int             // Offset of the dtz maps
+}
+
+// get returns the table for the side to move and file of the leading pawn passed
+func (tf *tableFile) get(t *table, stm int, file int) *pairsData {
+	if !t.hasPawns {
+		file = 0
+	}
+	return &tf.items[stm%tf.sides][file]
+}
+
+// load maps the file the first time is called. Returns if the file can be probed
+func (tf *tableFile) load(t *table) bool {
+	tf.once.Do(func() {
+		if tf.path == "" {
+			return
+		}
+		data, err := mapFile(tf.path)
+		if err != nil {
+			return
+		}
+		tf.data = data
+
+		// Corrupted files might lead to out of range offsets
+		defer func() {
+			if recover() != nil {
+				tf.loaded = false
+			}
+		}()
+		tf.loaded = tf.init(t)
+	})
+	return tf.loaded
+}
+
+// close unmaps the file
+func (tf *tableFile) close() {
+	if tf.data != nil {
+		unmapFile(tf.data)
+		tf.data = nil
+		tf.loaded = false
+	}
+}
+
+// init reads the headers of the file and sets the tables offsets. Returns if the file is valid
+func (tf *tableFile) init(t *table) bool {
+	data := tf.data
+	if len(data) < 5 || [4]byte(data[:4]) != tf.magic {
+		return false
+	}
+	if (data[4]&hasPawnsFlag != 0) != t.hasPawns || (!tf.isDTZ && (data[4]&splitFlag != 0) != (t.key != t.key2)) {
+		return false
+	}
+	p := 5
+
+	tf.sides = 1
+	if !tf.isDTZ && t.key != t.key2 {
+		tf.sides = 2
+	}
+	maxFile := 0
+	if t.hasPawns {
+		maxFile = 3
+	}
+	pawnsOnBothSides := t.hasPawns && t.pawnCount[1] > 0
+
+	for f := 0; f <= maxFile; f++ {
+		order := [2][2]int{{int(data[p] & 0xF), 0xF}, {int(data[p] >> 4), 0xF}}
+		p++
+		if pawnsOnBothSides {
+			order[0][1], order[1][1] = int(data[p]&0xF), int(data[p]>>4)
+			p++
+		}
+
+		for k := range t.pieceCount {
+			tf.items[0][f].pieces[k] = Piece(data[p] & 0xF)
+			tf.items[1][f].pieces[k] = Piece(data[p] >> 4)
+			p++
+		}
+
+		for i := range tf.sides {
+			tf.items[i][f].setGroups(t, order[i], f)
+		}
+	}
+	p += p & 1
+
+	for f := 0; f <= maxFile; f++ {
+		for i := range tf.sides {
+			p = tf.items[i][f].setSizes(data, p)
+		}
+	}
+
+	if tf.isDTZ {
+		p = tf.setDTZMap(t, p, maxFile)
+	}
+
+	for f := 0; f <= maxFile; f++ {
+		for i := range tf.sides {
+			tf.items[i][f].sparseIndex = p
+			p += tf.items[i][f].sparseIndexSize * 6
+		}
+	}
+	for f := 0; f <= maxFile; f++ {
+		for i := range tf.sides {
+			tf.items[i][f].blockLength = p
+			p += tf.items[i][f].blockLengthSize * 2
+		}
+	}
+	for f := 0; f <= maxFile; f++ {
+		for i := range tf.sides {
+			p = (p + 0x3F) &^ 0x3F
+			tf.items[i][f].data = p
+			p += tf.items[i][f].numBlocks * tf.items[i][f].blockSize
+		}
+	}
+
+	return p <= len(data)
+}
+
+// setDTZMap sets the offsets of the maps of the dtz values, sorted by frequency on the file
+func (tf *tableFile) setDTZMap(t *table, p int, maxFile int) int {
+	tf.dtzMap = p
+	for f := 0; f <= maxFile; f++ {
+		d := tf.get(t, 0, f)
+		if d.flags&mappedFlag == 0 {
+			continue
+		}
+
+		if d.flags&wideFlag != 0 {
+			p += p & 1
+			for i := range 4 {
+				d.mapIdx[i] = (p-tf.dtzMap)/2 + 1
+				p += 2*int(binary.LittleEndian.Uint16(tf.data[p:])) + 2
+			}
+		} else {
+			for i := range 4 {
+				d.mapIdx[i] = p - tf.dtzMap + 1
+				p += int(tf.data[p]) + 1
+			}
+		}
+	}
+	return p + p&1
+}
+
+// mapScore converts the value stored in the table to the wdl or the dtz value of the position
+func (tf *tableFile) mapScore(t *table, file int, value int, wdl WDL) int {
+	if !tf.isDTZ {
+		return value - 2
+	}
+
+	wdlMap := [5]int{1, 3, 0, 2, 0}
+	d := tf.get(t, 0, file)
+	if d.flags&mappedFlag != 0 {
+		index := d.mapIdx[wdlMap[wdl+2]] + value
+		if d.flags&wideFlag != 0 {
+			value = int(binary.LittleEndian.Uint16(tf.data[tf.dtzMap+2*index:]))
+		} else {
+			value = int(tf.data[tf.dtzMap+index])
+		}
+	}
+
+	// Convert the values stored in moves to plies
+	if (wdl == Win && d.flags&winPliesFlag == 0) || (wdl == Loss && d.flags&lossPliesFlag == 0) ||
+		wdl == CursedWin || wdl == BlessedLoss {
+		value *= 2
+	}
+	return value + 1
+}
+
+// pairsData has the data to decompress the values of a table. Values are compressed with recursive pairing (the most
+// frequent pairs of symbols are replaced by a new symbol) and then encoded with a canonical Huffman code, in blocks
+type pairsData struct {
+	flags           byte
+	pieces          [MaxPieces]Piece      // Order of the pieces on the index
+	groupLen        [MaxPieces + 1]int    // Number of pieces of each group encoded together, zero terminated
+	groupIdx        [MaxPieces + 1]uint64 // Factor of each group on the index. The last one is the size of the table
+	mapIdx          [4]int                // Offsets of the dtz maps of each wdl value
+	blockSize       int
+	span            int // Values between entries of the sparse index
+	numBlocks       int
+	maxSymLen       int
+	minSymLen       int // Also the value of single value tables
+	lowestSym       int // Offset of the lowest symbol of each length
+	btree           int // Offset of the left and right symbols of each symbol
+	symlen          []int
+	base64          []uint64 // Lowest code of each length, padded to 64 bits
+	sparseIndex     int      // Offset of the sparse index, with the block and the offset of every span values
+	sparseIndexSize int
+	blockLength     int // Offset of the number of values (minus one) of each block
+	blockLengthSize int
+	data            int // Offset of the compressed blocks
+}
+
+// setGroups sets the groups of pieces encoded together and the factors of each group on the index. Pieces of the
+// same type and color are a group, except the leading group: the leading pawns, the first 3 pieces when there are
+// unique pieces, or the kings otherwise. The order of the groups on the index is given by the file
+func (d *pairsData) setGroups(t *table, order [2]int, file int) {
+	n, firstLen := 0, 2
+	if t.hasPawns {
+		firstLen = 0
+	} else if t.hasUniquePieces {
+		firstLen = 3
+	}
+
+	d.groupLen[n] = 1
+	for i := 1; i < t.pieceCount; i++ {
+		firstLen--
+		if firstLen > 0 || d.pieces[i] == d.pieces[i-1] {
+			d.groupLen[n]++
+		} else {
+			n++
+			d.groupLen[n] = 1
+		}
+	}
+	n++
+	d.groupLen[n] = 0
+
+	pawnsOnBothSides := t.hasPawns && t.pawnCount[1] > 0
+	next, freeSquares := 1, 64-d.groupLen[0]
+	if pawnsOnBothSides {
+		next, freeSquares = 2, freeSquares-d.groupLen[1]
+	}
+
+	idx := uint64(1)
+	for k := 0; next < n || k == order[0] || k == order[1]; k++ {
+		switch k {
+		case order[0]:
+			d.groupIdx[0] = idx
+			switch {
+			case t.hasPawns:
+				idx *= uint64(leadPawnsSize[d.groupLen[0]][file])
+			case t.hasUniquePieces:
+				idx *= 31332
+			default:
+				idx *= 462
+			}
+		case order[1]:
+			d.groupIdx[1] = idx
+			idx *= binomial[d.groupLen[1]][48-d.groupLen[0]]
+		default:
+			d.groupIdx[next] = idx
+			idx *= binomial[d.groupLen[next]][freeSquares]
+			freeSquares -= d.groupLen[next]
+			next++
+		}
+	}
+	d.groupIdx[n] = idx
+}
+
+// setSizes reads the sizes of the compressed data and the Huffman code. Returns the offset after them
+func (d *pairsData) setSizes(data []byte, p int) int {
+	d.flags = data[p]
+	p++
+	if d.flags&singleValueFlag != 0 {
+		d.minSymLen = int(data[p])
+		return p + 1
+	}
+
+	n := 0
+	for d.groupLen[n] != 0 {
+		n++
+	}
+	tbSize := d.groupIdx[n]
+
+	d.blockSize = 1 << data[p]
+	d.span = 1 << data[p+1]
+	d.sparseIndexSize = int((tbSize + uint64(d.span) - 1) / uint64(d.span))
+	padding := int(data[p+2])
+	d.numBlocks = int(binary.LittleEndian.Uint32(data[p+3:]))
+	d.blockLengthSize = d.numBlocks + padding
+	d.maxSymLen = int(data[p+7])
+	d.minSymLen = int(data[p+8])
+	d.lowestSym = p + 9
+	p += 9
+
+	// Canonical Huffman code. Longer codes have lower values, so the lowest code of each length is computed from
+	// the next length, that has the lowest code at 0
+	d.base64 = make([]uint64, d.maxSymLen-d.minSymLen+1)
+	for i := len(d.base64) - 2; i >= 0; i-- {
+		d.base64[i] = (d.base64[i+1] + uint64(d.lowestSymbol(data, i)) - uint64(d.lowestSymbol(data, i+1))) / 2
+	}
+	for i := range d.base64 {
+		d.base64[i] <<= 64 - i - d.minSymLen
+	}
+	p += len(d.base64) * 2
+
+	symbols := int(binary.LittleEndian.Uint16(data[p:]))
+	p += 2
+	d.btree = p
+	d.symlen = make([]int, symbols)
+	visited := make([]bool, symbols)
+	for sym := range symbols {
+		if !visited[sym] {
+			d.symlen[sym] = d.setSymlen(data, sym, visited)
+		}
+	}
+
+	return p + symbols*3 + symbols&1
+}
+
+// setSymlen returns the number of values (minus one) the symbol passed expands into
+func (d *pairsData) setSymlen(data []byte, sym int, visited []bool) int {
+	visited[sym] = true
+	left, right := d.children(data, sym)
+	if right == 0xFFF {
+		return 0
+	}
+
+	if !visited[left] {
+		d.symlen[left] = d.setSymlen(data, left, visited)
+	}
+	if !visited[right] {
+		d.symlen[right] = d.setSymlen(data, right, visited)
+	}
+	return d.symlen[left] + d.symlen[right] + 1
+}
+
+// lowestSymbol returns the lowest symbol of the code length passed (minus the min length)
+func (d *pairsData) lowestSymbol(data []byte, length int) int {
+	return int(binary.LittleEndian.Uint16(data[d.lowestSym+2*length:]))
+}
+
+// children returns the left and right symbols of the symbol passed. Leaf symbols have 0xFFF as right symbol, and
+// the value of the symbol as left symbol
+func (d *pairsData) children(data []byte, sym int) (int, int) {
+	lr := data[d.btree+3*sym:]
+	return int(lr[1]&0xF)<<8 | int(lr[0]), int(lr[2])<<4 | int(lr[1]>>4)
+}
+
+// decompress returns the value stored at the index passed
+func (d *pairsData) decompress(data []byte, idx uint64) int {
+	if d.flags&singleValueFlag != 0 {
+		return d.minSymLen
+	}
+
+	// The sparse index has the block and the offset of the value at k * span + span / 2. From there, we move
+	// through the blocks until reaching the one with the value
+	k := int(idx / uint64(d.span))
+	block := int(binary.LittleEndian.Uint32(data[d.sparseIndex+6*k:]))
+	offset := int(binary.LittleEndian.Uint16(data[d.sparseIndex+6*k+4:]))
+	offset += int(idx%uint64(d.span)) - d.span/2
+
+	for offset < 0 {
+		block--
+		offset += d.blockLen(data, block) + 1
+	}
+	for offset > d.blockLen(data, block) {
+		offset -= d.blockLen(data, block) + 1
+		block++
+	}
+
+	// Read the symbols of the block until the one that contains the value
+	ptr := d.data + block*d.blockSize
+	buf64 := uint64(readBigEndian32(data, ptr))<<32 | uint64(readBigEndian32(data, ptr+4))
+	ptr += 8
+	buf64Size := 64
+	sym := 0
+
+	for {
+		length := 0
+		for buf64 < d.base64[length] {
+			length++
+		}
+		sym = int((buf64-d.base64[length])>>(64-length-d.minSymLen)) + d.lowestSymbol(data, length)
+
+		if offset < d.symlen[sym]+1 {
+			break
+		}
+		offset -= d.symlen[sym] + 1
+		length += d.minSymLen
+		buf64 <<= length
+		buf64Size -= length
+
+		if buf64Size <= 32 {
+			buf64Size += 32
+			buf64 |= uint64(readBigEndian32(data, ptr)) << (64 - buf64Size)
+			ptr += 4
+		}
+	}
+
+	// Expand the symbol into its pairs until reaching the value
+	for d.symlen[sym] != 0 {
+		left, right := d.children(data, sym)
+		if offset < d.symlen[left]+1 {
+			sym = left
+		} else {
+			offset -= d.symlen[left] + 1
+			sym = right
+		}
+	}
+
+	value, _ := d.children(data, sym)
+	return value
+}
+
+// blockLen returns the number of values (minus one) of the block passed
+func (d *pairsData) blockLen(data []byte, block int) int {
+	return int(binary.LittleEndian.Uint16(data[d.blockLength+2*block:]))
+}
+
+// readBigEndian32 reads a big endian uint32 at the offset passed. Bytes after the end of the file are zero, as
+// the last symbols of the last block might be followed by less than 4 bytes
+func readBigEndian32(data []byte, offset int) uint32 {
+	if offset+4 <= len(data) {
+		return binary.BigEndian.Uint32(data[offset:])
+	}
+
+	var buf [4]byte
+	if offset < len(data) {
+		copy(buf[:], data[offset:])
+	}
+	return binary.BigEndian.Uint32(buf[:])
+}
@@ -0,0 +1,731 @@
+package syzygy
+
+import (
+	"encoding/binary"
+	"flag"
+	"os"
+	"slices"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "generate the tables of the testdata directory")
+
+const testdataPath = "testdata"
+
+// kqk is a position of a king and a queen against a lone king. The queen is -1 when it has been captured
+type kqk struct {
+	whiteKing, queen, blackKing int
+}
+
+var (
+	kingDirections  = [][2]int{{-1, -1}, {-1, 0}, {-1, 1}, {0, -1}, {0, 1}, {1, -1}, {1, 0}, {1, 1}}
+	queenDirections = kingDirections
+)
+
+// step returns the square at the direction passed of the square, or -1 if it's outside the board
+func step(sq int, direction [2]int) int {
+	file, rank := sq%8+direction[0], sq/8+direction[1]
+	if file < 0 || file > 7 || rank < 0 || rank > 7 {
+		return -1
+	}
+	return rank*8 + file
+}
+
+// adjacent returns if the squares passed are next to each other
+func adjacent(sq1 int, sq2 int) bool {
+	return sq1 != sq2 && abs(sq1%8-sq2%8) <= 1 && abs(sq1/8-sq2/8) <= 1
+}
+
+// queenAttacks returns if the queen attacks the square passed, with the white king as the only blocker
+func (p kqk) queenAttacks(sq int) bool {
+	if p.queen < 0 {
+		return false
+	}
+	for _, direction := range queenDirections {
+		for to := step(p.queen, direction); to >= 0; to = step(to, direction) {
+			if to == sq {
+				return true
+			}
+			if to == p.whiteKing {
+				break
+			}
+		}
+	}
+	return false
+}
+
+// legal returns if the position is legal with the side to move passed
+func (p kqk) legal(blackToMove bool) bool {
+	if p.whiteKing == p.blackKing || p.whiteKing == p.queen || p.blackKing == p.queen {
+		return false
+	}
+	return !adjacent(p.whiteKing, p.blackKing) && (blackToMove || !p.queenAttacks(p.blackKing))
+}
+
+// whiteMoves returns the positions after the white moves
+func (p kqk) whiteMoves() []kqk {
+	positions := []kqk{}
+	for _, direction := range kingDirections {
+		to := step(p.whiteKing, direction)
+		if to >= 0 && to != p.queen && !adjacent(to, p.blackKing) {
+			positions = append(positions, kqk{to, p.queen, p.blackKing})
+		}
+	}
+	for _, direction := range queenDirections {
+		for to := step(p.queen, direction); to >= 0 && to != p.whiteKing && to != p.blackKing; to = step(to, direction) {
+			positions = append(positions, kqk{p.whiteKing, to, p.blackKing})
+		}
+	}
+	return positions
+}
+
+// blackMoves returns the positions after the black moves
+func (p kqk) blackMoves() []kqk {
+	positions := []kqk{}
+	for _, direction := range kingDirections {
+		to := step(p.blackKing, direction)
+		if to < 0 {
+			continue
+		}
+		next := kqk{p.whiteKing, p.queen, to}
+		if to == p.queen {
+			next.queen = -1
+		}
+		if !adjacent(to, p.whiteKing) && !next.queenAttacks(to) {
+			positions = append(positions, next)
+		}
+	}
+	return positions
+}
+
+// position returns the position to probe
+func (p kqk) position(blackToMove bool) *Position {
+	pos := &Position{BlackToMove: blackToMove}
+	pos.Board[p.whiteKing] = WhiteKing
+	pos.Board[p.blackKing] = BlackKing
+	if p.queen >= 0 {
+		pos.Board[p.queen] = WhiteQueen
+	}
+	return pos
+}
+
+// allKQK returns all the positions with the three pieces on different squares
+func allKQK() []kqk {
+	positions := []kqk{}
+	for whiteKing := range 64 {
+		for queen := range 64 {
+			for blackKing := range 64 {
+				if whiteKing != queen && whiteKing != blackKing && queen != blackKing {
+					positions = append(positions, kqk{whiteKing, queen, blackKing})
+				}
+			}
+		}
+	}
+	return positions
+}
+
+// solveKQK returns the plies to mate of the won positions with white to move, and the plies to be mated of the
+// lost positions with black to move. Drawn positions are not included
+func solveKQK() (map[kqk]int, map[kqk]int) {
+	wins, losses := map[kqk]int{}, map[kqk]int{}
+	positions := allKQK()
+
+	for _, p := range positions {
+		if p.legal(true) && p.queenAttacks(p.blackKing) && len(p.blackMoves()) == 0 {
+			losses[p] = 0
+		}
+	}
+
+	for plies := 1; ; plies += 2 {
+		found := false
+		for _, p := range positions {
+			if _, solved := wins[p]; solved || !p.legal(false) {
+				continue
+			}
+			for _, next := range p.whiteMoves() {
+				if distance, lost := losses[next]; lost && distance == plies-1 {
+					wins[p] = plies
+					found = true
+					break
+				}
+			}
+		}
+
+		for _, p := range positions {
+			if _, solved := losses[p]; solved || !p.legal(true) {
+				continue
+			}
+			moves, allLost := p.blackMoves(), true
+			for _, next := range moves {
+				if _, won := wins[next]; !won || next.queen < 0 {
+					allLost = false
+					break
+				}
+			}
+			if len(moves) > 0 && allLost {
+				losses[p] = plies + 1
+			}
+		}
+
+		if !found {
+			return wins, losses
+		}
+	}
+}
+
+// generateKQK writes the KQvK wdl and dtz files. The dtz file only stores the positions with white to move
+func generateKQK(t *testing.T) {
+	wins, losses := solveKQK()
+	pieces := []Piece{WhiteQueen, WhiteKing, BlackKing}
+
+	wdl := newTestTableFile("KQvK", false, pieces, [2]byte{})
+	dtz := newTestTableFile("KQvK", true, pieces, [2]byte{winPliesFlag})
+	wdlValues, dtzValues := newValues(wdl), newValues(dtz)
+
+	for _, p := range allKQK() {
+		for _, blackToMove := range []bool{false, true} {
+			if !p.legal(blackToMove) {
+				continue
+			}
+			value := int(Win)
+			if blackToMove {
+				value = int(Draw)
+				if _, lost := losses[p]; lost {
+					value = int(Loss)
+				}
+			}
+			setValue(t, wdl, p.position(blackToMove), &wdlValues, value+2)
+
+			if !blackToMove {
+				if _, won := wins[p]; !won {
+					t.Fatalf("Expected a win for white to move at %v", p)
+				}
+				setValue(t, dtz, p.position(blackToMove), &dtzValues, wins[p]-1)
+			}
+		}
+	}
+
+	writeTable(t, testdataPath+"/KQvK"+WDLSuffix, wdl, wdlValues)
+	writeTable(t, testdataPath+"/KQvK"+DTZSuffix, dtz, dtzValues)
+}
+
+// kpk is a position of a king and a pawn against a lone king
+type kpk struct {
+	whiteKing, pawn, blackKing int
+}
+
+var rookDirections = [][2]int{{-1, 0}, {0, -1}, {0, 1}, {1, 0}}
+
+// index returns the index of the position on the solved positions
+func (p kpk) index() int {
+	return (p.whiteKing*64+p.pawn)*64 + p.blackKing
+}
+
+// pawnAttacks returns if the pawn attacks the square passed
+func (p kpk) pawnAttacks(sq int) bool {
+	return sq == step(p.pawn, [2]int{-1, 1}) || sq == step(p.pawn, [2]int{1, 1})
+}
+
+// legal returns if the position is legal with the side to move passed
+func (p kpk) legal(blackToMove bool) bool {
+	if p.whiteKing == p.blackKing || p.whiteKing == p.pawn || p.blackKing == p.pawn || p.pawn < 8 || p.pawn >= 56 {
+		return false
+	}
+	return !adjacent(p.whiteKing, p.blackKing) && (blackToMove || !p.pawnAttacks(p.blackKing))
+}
+
+// whiteMoves returns the positions after the white king moves and the pawn pushes that don't promote
+func (p kpk) whiteMoves() (kingMoves []kpk, pawnMoves []kpk) {
+	for _, direction := range kingDirections {
+		to := step(p.whiteKing, direction)
+		if to >= 0 && to != p.pawn && !adjacent(to, p.blackKing) {
+			kingMoves = append(kingMoves, kpk{to, p.pawn, p.blackKing})
+		}
+	}
+	// Single and double pushes. Pushes to the last rank are promotions
+	for to := p.pawn + 8; to < 56 && to != p.whiteKing && to != p.blackKing; to += 8 {
+		pawnMoves = append(pawnMoves, kpk{p.whiteKing, to, p.blackKing})
+		if p.pawn >= 16 || to == p.pawn+16 {
+			break
+		}
+	}
+	return
+}
+
+// blackMoves returns the positions after the black moves, and if the black king can capture the pawn
+func (p kpk) blackMoves() (positions []kpk, capture bool) {
+	for _, direction := range kingDirections {
+		to := step(p.blackKing, direction)
+		if to < 0 || adjacent(to, p.whiteKing) || p.pawnAttacks(to) {
+			continue
+		}
+		if to == p.pawn {
+			capture = true
+			continue
+		}
+		positions = append(positions, kpk{p.whiteKing, p.pawn, to})
+	}
+	return
+}
+
+// promotionWins returns if promoting the pawn to a queen or a rook wins. KQvK and KRvK are won unless the black
+// king captures the promoted piece or it's stalemate
+func (p kpk) promotionWins() bool {
+	to := p.pawn + 8
+	if p.pawn < 48 || to == p.whiteKing || to == p.blackKing {
+		return false
+	}
+	if adjacent(to, p.blackKing) && !adjacent(to, p.whiteKing) {
+		return false
+	}
+
+	for _, directions := range [][][2]int{queenDirections, rookDirections} {
+		attacks := func(sq int) bool {
+			for _, direction := range directions {
+				for next := step(to, direction); next >= 0 && next != p.whiteKing; next = step(next, direction) {
+					if next == sq {
+						return true
+					}
+				}
+			}
+			return false
+		}
+		for _, direction := range kingDirections {
+			next := step(p.blackKing, direction)
+			if next >= 0 && !adjacent(next, p.whiteKing) && !attacks(next) {
+				return true
+			}
+		}
+		if attacks(p.blackKing) {
+			return true
+		}
+	}
+	return false
+}
+
+// position returns the position to probe
+func (p kpk) position(blackToMove bool) *Position {
+	pos := &Position{BlackToMove: blackToMove}
+	pos.Board[p.whiteKing] = WhiteKing
+	pos.Board[p.pawn] = WhitePawn
+	pos.Board[p.blackKing] = BlackKing
+	return pos
+}
+
+// solveKPK returns the won positions with white to move, with the plies to the next zeroing move (the dtz), and
+// the lost positions with black to move
+func solveKPK() (wins map[kpk]int, losses map[kpk]bool) {
+	var won [64 * 64 * 64]bool
+	var lost [64 * 64 * 64]bool
+	positions := []kpk{}
+	for whiteKing := range 64 {
+		for pawn := 8; pawn < 56; pawn++ {
+			for blackKing := range 64 {
+				positions = append(positions, kpk{whiteKing, pawn, blackKing})
+			}
+		}
+	}
+
+	// Win, draw and loss of each position
+	for changed := true; changed; {
+		changed = false
+		for _, p := range positions {
+			if !won[p.index()] && p.legal(false) {
+				kingMoves, pawnMoves := p.whiteMoves()
+				wins := p.promotionWins()
+				for _, next := range append(kingMoves, pawnMoves...) {
+					wins = wins || lost[next.index()]
+				}
+				if wins {
+					won[p.index()], changed = true, true
+				}
+			}
+			if !lost[p.index()] && p.legal(true) {
+				moves, capture := p.blackMoves()
+				loses := !capture && (len(moves) > 0 || p.pawnAttacks(p.blackKing))
+				for _, next := range moves {
+					loses = loses && won[next.index()]
+				}
+				if loses {
+					lost[p.index()], changed = true, true
+				}
+			}
+		}
+	}
+
+	// Distance to zeroing of the wins. The pawn moves that keep the win are zeroing moves
+	wins, losses = map[kpk]int{}, map[kpk]bool{}
+	dtzLosses := map[kpk]int{}
+	for _, p := range positions {
+		if lost[p.index()] && p.legal(true) {
+			losses[p] = true
+		}
+		if !won[p.index()] || !p.legal(false) {
+			continue
+		}
+		_, pawnMoves := p.whiteMoves()
+		zeroing := p.promotionWins()
+		for _, next := range pawnMoves {
+			zeroing = zeroing || lost[next.index()]
+		}
+		if zeroing {
+			wins[p] = 1
+		}
+	}
+
+	for plies := 1; ; plies += 2 {
+		found := false
+		for p := range losses {
+			if _, solved := dtzLosses[p]; solved {
+				continue
+			}
+			moves, _ := p.blackMoves()
+			longest := 0
+			for _, next := range moves {
+				if dtz, solved := wins[next]; solved {
+					longest = max(longest, dtz)
+				} else {
+					longest = -1
+					break
+				}
+			}
+			if len(moves) > 0 && longest > 0 {
+				dtzLosses[p] = longest + 1
+			}
+		}
+
+		for _, p := range positions {
+			if _, solved := wins[p]; solved || !won[p.index()] || !p.legal(false) {
+				continue
+			}
+			kingMoves, _ := p.whiteMoves()
+			for _, next := range kingMoves {
+				if dtz, solved := dtzLosses[next]; solved && (wins[p] == 0 || dtz+1 < wins[p]) {
+					wins[p] = dtz + 1
+					found = true
+				}
+			}
+		}
+
+		if !found {
+			return wins, losses
+		}
+	}
+}
+
+// generateKPK writes the KPvK wdl and dtz files, with a table for each file of the pawn. The dtz file only stores
+// the positions with white to move
+func generateKPK(t *testing.T) {
+	wins, losses := solveKPK()
+	pieces := []Piece{WhitePawn, WhiteKing, BlackKing}
+
+	wdl := newTestTableFile("KPvK", false, pieces, [2]byte{})
+	dtz := newTestTableFile("KPvK", true, pieces, [2]byte{winPliesFlag})
+	wdlValues, dtzValues := newValues(wdl), newValues(dtz)
+
+	for whiteKing := range 64 {
+		for pawn := 8; pawn < 56; pawn++ {
+			for blackKing := range 64 {
+				p := kpk{whiteKing, pawn, blackKing}
+				if p.legal(false) {
+					value := int(Draw)
+					if dtzValue, won := wins[p]; won {
+						value = int(Win)
+						setValue(t, dtz, p.position(false), &dtzValues, dtzValue-1)
+					}
+					setValue(t, wdl, p.position(false), &wdlValues, value+2)
+				}
+				if p.legal(true) {
+					value := int(Draw)
+					if losses[p] {
+						value = int(Loss)
+					}
+					setValue(t, wdl, p.position(true), &wdlValues, value+2)
+				}
+			}
+		}
+	}
+
+	writeTable(t, testdataPath+"/KPvK"+WDLSuffix, wdl, wdlValues)
+	writeTable(t, testdataPath+"/KPvK"+DTZSuffix, dtz, dtzValues)
+}
+
+// newTestTableFile returns a table file with the pieces order and flags of each side passed
+func newTestTableFile(name string, isDTZ bool, pieces []Piece, flags [2]byte) *tableFile {
+	tf := &tableFile{isDTZ: isDTZ, sides: 2}
+	if isDTZ {
+		tf.sides = 1
+	}
+	t := newTable(name, "", "")
+	maxFile := 0
+	if t.hasPawns {
+		maxFile = 3
+	}
+	for f := 0; f <= maxFile; f++ {
+		for i := range tf.sides {
+			copy(tf.items[i][f].pieces[:], pieces)
+			tf.items[i][f].flags = flags[i]
+			tf.items[i][f].setGroups(t, [2]int{0, 0xF}, f)
+		}
+	}
+	return tf
+}
+
+// tableValues are the values of each table of a file, indexed by the file of the leading pawn and the side to move
+type tableValues [4][2][]int
+
+// newValues returns the values of the tables of the file passed, with all of them unset (-1)
+func newValues(tf *tableFile) (values tableValues) {
+	for f := range 4 {
+		for side := range tf.sides {
+			d := &tf.items[side][f]
+			if d.groupLen[0] == 0 {
+				continue
+			}
+			values[f][side] = make([]int, d.groupIdx[slices.Index(d.groupLen[:], 0)])
+			for i := range values[f][side] {
+				values[f][side][i] = -1
+			}
+		}
+	}
+	return
+}
+
+// setValue sets the value of the index of the position, checking that positions with the same index have the
+// same value
+func setValue(t *testing.T, tf *tableFile, pos *Position, values *tableValues, value int) {
+	idx, d, file, result := tf.encode(newTable(materialKey(&pos.Board), "", ""), pos)
+	if result != Found {
+		t.Fatalf("Expected: %v, got: %v", Found, result)
+	}
+	side := 0
+	if d == &tf.items[1][file] {
+		side = 1
+	}
+	table := values[file][side]
+	if table[idx] != -1 && table[idx] != value {
+		t.Fatalf("Expected: %v, got: %v at index %v", table[idx], value, idx)
+	}
+	table[idx] = value
+}
+
+// writeTable writes the table file with the values of each file and side. Indexes without a value (broken
+// positions) get the most frequent value. Values are encoded with a canonical Huffman code, without pairing symbols
+func writeTable(t *testing.T, path string, tf *tableFile, values tableValues) {
+	const blockSize, span = 64, 64
+	magic := wdlMagic
+	if tf.isDTZ {
+		magic = dtzMagic
+	}
+
+	// Tables are stored by file and then by side to move, with the order and the pieces of each file on the header
+	header := append(magic[:], splitFlag)
+	type fileTable struct {
+		flags  byte
+		values []int
+	}
+	tables := []fileTable{}
+	if values[1][0] != nil { // Tables with pawns have a table for each file
+		header[4] |= hasPawnsFlag
+	}
+	for f := range 4 {
+		if values[f][0] == nil {
+			continue
+		}
+		header = append(header, 0)
+		for _, piece := range tf.items[0][f].pieces {
+			if piece != NoPiece {
+				header = append(header, byte(piece)|byte(piece)<<4)
+			}
+		}
+		for side := range tf.sides {
+			tables = append(tables, fileTable{tf.items[side][f].flags, values[f][side]})
+		}
+	}
+	header = pad(header, 2)
+
+	var sparseIndexes, blockLengths, blocks [][]byte
+	for _, table := range tables {
+		flags, sideValues := table.flags, table.values
+		frequencies := map[int]int{}
+		for _, value := range sideValues {
+			frequencies[value]++
+		}
+		delete(frequencies, -1)
+		mostFrequent := -1
+		for value, frequency := range frequencies {
+			if mostFrequent == -1 || frequency > frequencies[mostFrequent] ||
+				(frequency == frequencies[mostFrequent] && value < mostFrequent) {
+				mostFrequent = value
+			}
+		}
+		for i := range sideValues {
+			if sideValues[i] == -1 {
+				sideValues[i] = mostFrequent
+			}
+		}
+
+		if len(frequencies) == 1 {
+			header = append(header, flags|singleValueFlag, byte(mostFrequent))
+			sparseIndexes, blockLengths, blocks = append(sparseIndexes, nil), append(blockLengths, nil), append(blocks, nil)
+			continue
+		}
+
+		lengths := huffmanLengths(frequencies)
+		symbols := make([]int, 0, len(lengths))
+		for value := range lengths {
+			symbols = append(symbols, value)
+		}
+		slices.SortFunc(symbols, func(a, b int) int {
+			if lengths[a] != lengths[b] {
+				return lengths[b] - lengths[a]
+			}
+			return a - b
+		})
+		minLength, maxLength := lengths[symbols[len(symbols)-1]], lengths[symbols[0]]
+
+		// Longer codes get the lowest values. The lowest symbol of each length is the number of longer symbols
+		lowestSym := make([]int, maxLength-minLength+1)
+		base := make([]uint64, maxLength-minLength+1)
+		for l := minLength; l <= maxLength; l++ {
+			for _, sym := range symbols {
+				if lengths[sym] > l {
+					lowestSym[l-minLength]++
+				}
+			}
+		}
+		for l := maxLength - 1; l >= minLength; l-- {
+			next := base[l+1-minLength] + uint64(lowestSym[l-minLength]-lowestSym[l+1-minLength])
+			if next%2 != 0 {
+				t.Fatalf("Expected a complete Huffman code for %v", lengths)
+			}
+			base[l-minLength] = next / 2
+		}
+		codes := map[int]uint64{}
+		for id, sym := range symbols {
+			l := lengths[sym] - minLength
+			codes[sym] = base[l] + uint64(id-lowestSym[l])
+		}
+
+		// Fill the blocks with the codes of the values, most significant bits first
+		var blockStarts []int
+		var data []byte
+		var bits []bool
+		var counts []int
+		flush := func() {
+			block := make([]byte, blockSize)
+			for i, bit := range bits {
+				if bit {
+					block[i/8] |= 1 << (7 - i%8)
+				}
+			}
+			data = append(data, block...)
+			bits = bits[:0]
+		}
+		for i, value := range sideValues {
+			if len(bits) == 0 || len(bits)+lengths[value] > blockSize*8 {
+				if len(bits) > 0 {
+					flush()
+				}
+				blockStarts = append(blockStarts, i)
+				counts = append(counts, 0)
+			}
+			for b := lengths[value] - 1; b >= 0; b-- {
+				bits = append(bits, codes[value]>>b&1 == 1)
+			}
+			counts[len(counts)-1]++
+		}
+		flush()
+
+		sparseIndex := []byte{}
+		for k := 0; k*span < len(sideValues); k++ {
+			position := k*span + span/2
+			block, _ := slices.BinarySearch(blockStarts, position+1)
+			block--
+			sparseIndex = binary.LittleEndian.AppendUint32(sparseIndex, uint32(block))
+			sparseIndex = binary.LittleEndian.AppendUint16(sparseIndex, uint16(position-blockStarts[block]))
+		}
+		blockLength := []byte{}
+		for _, count := range counts {
+			blockLength = binary.LittleEndian.AppendUint16(blockLength, uint16(count-1))
+		}
+
+		header = append(header, flags, 6, 6, 0)
+		header = binary.LittleEndian.AppendUint32(header, uint32(len(counts)))
+		header = append(header, byte(maxLength), byte(minLength))
+		for _, sym := range lowestSym {
+			header = binary.LittleEndian.AppendUint16(header, uint16(sym))
+		}
+		header = binary.LittleEndian.AppendUint16(header, uint16(len(symbols)))
+		for _, sym := range symbols {
+			header = append(header, byte(sym), byte(sym>>8&0xF)|0xF0, 0xFF)
+		}
+		if len(symbols)%2 != 0 {
+			header = append(header, 0)
+		}
+
+		sparseIndexes, blockLengths, blocks = append(sparseIndexes, sparseIndex), append(blockLengths, blockLength), append(blocks, data)
+	}
+
+	file := header
+	if tf.isDTZ {
+		file = pad(file, 2)
+	}
+	for _, sparseIndex := range sparseIndexes {
+		file = append(file, sparseIndex...)
+	}
+	for _, blockLength := range blockLengths {
+		file = append(file, blockLength...)
+	}
+	for _, data := range blocks {
+		file = append(pad(file, 64), data...)
+	}
+
+	if err := os.WriteFile(path, file, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// pad returns the data with zeros appended up to a multiple of the size passed
+func pad(data []byte, size int) []byte {
+	for len(data)%size != 0 {
+		data = append(data, 0)
+	}
+	return data
+}
+
+// huffmanLengths returns the code length of each value with the frequencies passed
+func huffmanLengths(frequencies map[int]int) map[int]int {
+	type node struct {
+		frequency int
+		values    []int
+	}
+	nodes := []node{}
+	for value, frequency := range frequencies {
+		nodes = append(nodes, node{frequency, []int{value}})
+	}
+
+	lengths := map[int]int{}
+	for len(nodes) > 1 {
+		slices.SortFunc(nodes, func(a, b node) int {
+			if a.frequency != b.frequency {
+				return a.frequency - b.frequency
+			}
+			return slices.Min(a.values) - slices.Min(b.values)
+		})
+		merged := node{nodes[0].frequency + nodes[1].frequency, append(slices.Clone(nodes[0].values), nodes[1].values...)}
+		for _, value := range merged.values {
+			lengths[value]++
+		}
+		nodes = append([]node{merged}, nodes[2:]...)
+	}
+	return lengths
+}
+
+func TestGenerateTables(t *testing.T) {
+	if !*update {
+		t.Skip("Run with -update to generate the tables")
+	}
+	generateKQK(t)
+	generateKPK(t)
+}
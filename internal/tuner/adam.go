@@ -3,6 +3,8 @@ package tuner
 import (
 	"fmt"
 	"math"
+
+	"github.com/gabtar/aconcagua/internal/engine"
 )
 
 // AdamOptimizer implements the Adam optimization algorithm
@@ -32,14 +34,21 @@ var gradients = [TuneableParams]float64{}
 
 // Update updates the parameters
 func (adam *AdamOptimizer) Update(params *[TuneableParams]float64, gradients *[]float64) {
+	adam.update(params[:], *gradients)
+}
+
+// update updates the parameters of any size passed
+func (adam *AdamOptimizer) update(params []float64, gradients []float64) {
 	adam.t++
+	mCorrection := 1 - math.Pow(adam.beta1, float64(adam.t))
+	vCorrection := 1 - math.Pow(adam.beta2, float64(adam.t))
 
 	for i := range params {
-		adam.m[i] = adam.beta1*adam.m[i] + (1-adam.beta1)*(*gradients)[i]
-		adam.v[i] = adam.beta2*adam.v[i] + (1-adam.beta2)*(*gradients)[i]*(*gradients)[i]
+		adam.m[i] = adam.beta1*adam.m[i] + (1-adam.beta1)*gradients[i]
+		adam.v[i] = adam.beta2*adam.v[i] + (1-adam.beta2)*gradients[i]*gradients[i]
 
-		mHat := adam.m[i] / (1 - math.Pow(adam.beta1, float64(adam.t)))
-		vHat := adam.v[i] / (1 - math.Pow(adam.beta2, float64(adam.t)))
+		mHat := adam.m[i] / mCorrection
+		vHat := adam.v[i] / vCorrection
 
 		params[i] -= adam.learningRate * mHat / (math.Sqrt(vHat) + adam.epsilon)
 	}
@@ -59,10 +68,19 @@ func ComputeGradients(entry *DatasetEntry, params [TuneableParams]float64, K flo
 	error := predicted - actual
 	lossGradient := 2 * error * K * predicted * (1 - predicted)
 
+	scale, scaleIndex := 1.0, -1
 	for _, attr := range entry.Weights {
+		// The weights after a scale factor are multiplied by it, so the scale gets the gradient of their score
+		if attr.paramIndex >= FirstScaleParam {
+			scale, scaleIndex = params[attr.paramIndex]/engine.ScaleNormal, int(attr.paramIndex)
+			continue
+		}
 		if attr.paramIndex >= 0 && int(attr.paramIndex) < len(gradients) {
 			evalGradient := float64(attr.weight) / 62.0
-			gradients[attr.paramIndex] += lossGradient * evalGradient
+			gradients[attr.paramIndex] += lossGradient * evalGradient * scale
+			if scaleIndex >= 0 {
+				gradients[scaleIndex] += lossGradient * evalGradient * params[attr.paramIndex] / engine.ScaleNormal
+			}
 		}
 	}
 
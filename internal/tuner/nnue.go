@@ -0,0 +1,348 @@
+package tuner
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/gabtar/aconcagua/internal/engine"
+)
+
+// Layout of the network params of the trainer, in the units of the float network (activations between 0 and 1)
+const (
+	nnueHidden          = engine.NNUEHiddenSize
+	nnueBiasIndex       = engine.NNUEInputSize * nnueHidden
+	nnueOutputIndex     = nnueBiasIndex + nnueHidden
+	nnueOutputBiasIndex = nnueOutputIndex + 2*nnueHidden
+	nnueParams          = nnueOutputBiasIndex + 1
+
+	// Weights are clipped to keep the quantized network inside the int16 range, even with all the pieces
+	nnueMaxFeatureWeight = 1.98
+	nnueMaxOutputWeight  = 127.0
+)
+
+// NNUEEntry is a training example of the network, with the active inputs from the perspective of each side
+type NNUEEntry struct {
+	features [2][]int16 // Inputs from the white and black perspective
+	side     engine.Color
+	result   float64 // Result of the game from the side to move
+	score    float64 // Search score from the side to move, in centipawns
+	hasScore bool
+}
+
+// NewNNUEDataset returns the training examples of the network for the dataset entries
+func NewNNUEDataset(dataset []DatasetEntry) []NNUEEntry {
+	entries := make([]NNUEEntry, len(dataset))
+	pos := engine.NewPosition()
+
+	for i, entry := range dataset {
+		pos.LoadFromFenString(entry.Fen)
+		e := &entries[i]
+		e.side = pos.Turn
+		e.result, e.score, e.hasScore = entry.Result, entry.Score, entry.HasScore
+		if pos.Turn == engine.Black {
+			e.result, e.score = 1-e.result, -e.score
+		}
+
+		for piece, bb := range pos.Pieces {
+			for bb > 0 {
+				sq := engine.Bsf(bb.NextBit())
+				e.features[engine.White] = append(e.features[engine.White], int16(engine.NNUEFeature(piece, sq, engine.White)))
+				e.features[engine.Black] = append(e.features[engine.Black], int16(engine.NNUEFeature(piece, sq, engine.Black)))
+			}
+		}
+	}
+	return entries
+}
+
+// NNUETrainer trains the network of the engine with quantization-aware training. The forward pass uses the params
+// rounded to the steps of the quantized network, and the gradients pass straight through the rounding to the
+// float params, so the exported network evaluates like the trained one
+type NNUETrainer struct {
+	params        []float64
+	quantized     []float64 // Params rounded to the steps of the quantized network, used by the forward pass
+	adam          *AdamOptimizer
+	ScalingFactor float64 // Scaling factor of the scores to win probabilities
+	ScoreWeight   float64 // Weight of the search score in the target, when the entry has one. The rest is the result
+	BatchSize     int
+	Workers       int
+}
+
+// NewNNUETrainer returns a trainer that starts from the network passed, like the engine.BootstrapNetwork or a
+// checkpoint. Unused neurons start with small random weights
+func NewNNUETrainer(network *engine.Network, learningRate float64, seed uint64) *NNUETrainer {
+	trainer := &NNUETrainer{
+		params:        make([]float64, nnueParams),
+		quantized:     make([]float64, nnueParams),
+		adam:          NewAdamOptimizer(nnueParams, learningRate),
+		ScalingFactor: ScalingFactor,
+		ScoreWeight:   0.5,
+		BatchSize:     16384,
+		Workers:       4,
+	}
+	rng := rand.New(rand.NewPCG(seed, seed))
+
+	for neuron := range nnueHidden {
+		unused := network.FeatureBias[neuron] == 0
+		for feature := range engine.NNUEInputSize {
+			unused = unused && network.FeatureWeights[feature][neuron] == 0
+		}
+
+		for feature := range engine.NNUEInputSize {
+			weight := float64(network.FeatureWeights[feature][neuron]) / engine.NNUEQA
+			if unused {
+				weight = (rng.Float64() - 0.5) / 50
+			}
+			trainer.params[feature*nnueHidden+neuron] = weight
+		}
+		trainer.params[nnueBiasIndex+neuron] = float64(network.FeatureBias[neuron]) / engine.NNUEQA
+		if unused {
+			trainer.params[nnueBiasIndex+neuron] = 0.5
+		}
+	}
+	for i := range 2 * nnueHidden {
+		trainer.params[nnueOutputIndex+i] = float64(network.OutputWeights[i]) / engine.NNUEQB
+	}
+	trainer.params[nnueOutputBiasIndex] = float64(network.OutputBias) / (engine.NNUEQA * engine.NNUEQB)
+
+	// The params start at random offsets inside their rounding steps. Otherwise all of them would start on the
+	// steps and cross the rounding together, as Adam moves them at the same rate
+	for i := range trainer.params {
+		trainer.params[i] += (rng.Float64() - 0.5) / quantizationStep(i)
+	}
+	trainer.quantize()
+
+	return trainer
+}
+
+// Network returns the params quantized to the network loaded by the engine
+func (t *NNUETrainer) Network() *engine.Network {
+	network := &engine.Network{}
+	for feature := range engine.NNUEInputSize {
+		for neuron := range nnueHidden {
+			network.FeatureWeights[feature][neuron] = int16(math.Round(t.params[feature*nnueHidden+neuron] * engine.NNUEQA))
+		}
+	}
+	for neuron := range nnueHidden {
+		network.FeatureBias[neuron] = int16(math.Round(t.params[nnueBiasIndex+neuron] * engine.NNUEQA))
+	}
+	for i := range 2 * nnueHidden {
+		network.OutputWeights[i] = int16(math.Round(t.params[nnueOutputIndex+i] * engine.NNUEQB))
+	}
+	network.OutputBias = int32(math.Round(t.params[nnueOutputBiasIndex] * engine.NNUEQA * engine.NNUEQB))
+	return network
+}
+
+// clip keeps the params inside the range of the quantized network
+func (t *NNUETrainer) clip() {
+	for i := range nnueOutputIndex {
+		t.params[i] = max(-nnueMaxFeatureWeight, min(nnueMaxFeatureWeight, t.params[i]))
+	}
+	for i := nnueOutputIndex; i < nnueOutputBiasIndex; i++ {
+		t.params[i] = max(-nnueMaxOutputWeight, min(nnueMaxOutputWeight, t.params[i]))
+	}
+}
+
+// quantizationStep returns the inverse of the step of the param passed on the quantized network: QA for the
+// feature weights and biases, QB for the output weights and QA*QB for the output bias
+func quantizationStep(i int) float64 {
+	switch {
+	case i == nnueOutputBiasIndex:
+		return engine.NNUEQA * engine.NNUEQB
+	case i >= nnueOutputIndex:
+		return engine.NNUEQB
+	default:
+		return engine.NNUEQA
+	}
+}
+
+// quantize rounds the params to the steps of the quantized network
+func (t *NNUETrainer) quantize() {
+	for i, param := range t.params {
+		step := quantizationStep(i)
+		t.quantized[i] = math.Round(param*step) / step
+	}
+}
+
+// evaluate returns the score in centipawns of the network for the entry, relative to the side to move, and the
+// hidden layer of each side before the activation. It uses the quantized params, like the exported network
+func (t *NNUETrainer) evaluate(entry *NNUEEntry) (float64, [2][nnueHidden]float64) {
+	var hidden [2][nnueHidden]float64
+	for side := range 2 {
+		copy(hidden[side][:], t.quantized[nnueBiasIndex:nnueOutputIndex])
+		for _, feature := range entry.features[side] {
+			weights := t.quantized[int(feature)*nnueHidden : int(feature+1)*nnueHidden]
+			for neuron := range nnueHidden {
+				hidden[side][neuron] += weights[neuron]
+			}
+		}
+	}
+
+	us, them := entry.side, entry.side^1
+	output := t.quantized[nnueOutputBiasIndex]
+	for neuron := range nnueHidden {
+		output += max(0, min(1, hidden[us][neuron])) * t.quantized[nnueOutputIndex+neuron]
+		output += max(0, min(1, hidden[them][neuron])) * t.quantized[nnueOutputIndex+nnueHidden+neuron]
+	}
+	return output * engine.NNUEScale, hidden
+}
+
+// target returns the expected win probability of the entry: the result of the game, blended with the win
+// probability of the search score when there is one
+func (t *NNUETrainer) target(entry *NNUEEntry) float64 {
+	if !entry.hasScore {
+		return entry.result
+	}
+	return t.ScoreWeight*sigmoid(t.ScalingFactor*entry.score) + (1-t.ScoreWeight)*entry.result
+}
+
+// backward adds the gradients of the square error of the entry to the gradients passed, and returns the error.
+// The rounding of the params is skipped by the gradients (straight-through estimator)
+func (t *NNUETrainer) backward(entry *NNUEEntry, gradients []float64) float64 {
+	eval, hidden := t.evaluate(entry)
+	predicted := sigmoid(t.ScalingFactor * eval)
+	errorValue := predicted - t.target(entry)
+
+	// Gradient of the error by the output of the network, where the score is the output by NNUEScale
+	outputGradient := 2 * errorValue * predicted * (1 - predicted) * t.ScalingFactor * engine.NNUEScale
+	gradients[nnueOutputBiasIndex] += outputGradient
+
+	perspectives := [2]engine.Color{entry.side, entry.side ^ 1}
+	var hiddenGradients [2][nnueHidden]float64
+	for i, side := range perspectives {
+		for neuron := range nnueHidden {
+			value := hidden[side][neuron]
+			outputIndex := i*nnueHidden + neuron
+			gradients[nnueOutputIndex+outputIndex] += outputGradient * max(0, min(1, value))
+
+			// The clipped activation only passes the gradient inside its range
+			if value > 0 && value < 1 {
+				hiddenGradients[side][neuron] = outputGradient * t.quantized[nnueOutputIndex+outputIndex]
+				gradients[nnueBiasIndex+neuron] += hiddenGradients[side][neuron]
+			}
+		}
+	}
+
+	for side := range 2 {
+		for _, feature := range entry.features[side] {
+			featureGradients := gradients[int(feature)*nnueHidden : int(feature+1)*nnueHidden]
+			for neuron := range nnueHidden {
+				featureGradients[neuron] += hiddenGradients[side][neuron]
+			}
+		}
+	}
+	return errorValue * errorValue
+}
+
+// trainBatch updates the params with the mean gradients of the batch, computed by the workers in parallel.
+// Returns the total square error of the batch
+func (t *NNUETrainer) trainBatch(batch []NNUEEntry, workerGradients [][]float64) float64 {
+	var wg sync.WaitGroup
+	squareErrors := make([]float64, t.Workers)
+
+	for w := range t.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			gradients := workerGradients[w]
+			clear(gradients)
+			for i := w; i < len(batch); i += t.Workers {
+				squareErrors[w] += t.backward(&batch[i], gradients)
+			}
+		}()
+	}
+	wg.Wait()
+
+	gradients := workerGradients[0]
+	for _, other := range workerGradients[1:] {
+		for i := range gradients {
+			gradients[i] += other[i]
+		}
+	}
+	for i := range gradients {
+		gradients[i] /= float64(len(batch))
+	}
+	t.adam.update(t.params, gradients)
+	t.clip()
+	t.quantize()
+
+	totalError := 0.0
+	for _, e := range squareErrors {
+		totalError += e
+	}
+	return totalError
+}
+
+// Train trains the network for the epochs passed, shuffling the dataset on each one. A checkpoint of the
+// quantized network is saved on the directory passed every checkpointEvery epochs and at the end
+func (t *NNUETrainer) Train(dataset []NNUEEntry, epochs int, checkpointDir string, checkpointEvery int) error {
+	workerGradients := make([][]float64, t.Workers)
+	for w := range workerGradients {
+		workerGradients[w] = make([]float64, nnueParams)
+	}
+	rng := rand.New(rand.NewPCG(uint64(len(dataset)), 0))
+
+	fmt.Printf("Starting NNUE training with %d parameters, %d positions, K=%.6f\n", nnueParams, len(dataset), t.ScalingFactor)
+	for epoch := 1; epoch <= epochs; epoch++ {
+		rng.Shuffle(len(dataset), func(i, j int) { dataset[i], dataset[j] = dataset[j], dataset[i] })
+
+		totalError := 0.0
+		for start := 0; start < len(dataset); start += t.BatchSize {
+			totalError += t.trainBatch(dataset[start:min(start+t.BatchSize, len(dataset))], workerGradients)
+		}
+		fmt.Printf("Epoch %3d: MSE = %.8f, LR = %.6f\n", epoch, totalError/float64(len(dataset)), t.adam.learningRate)
+
+		if epoch%checkpointEvery == 0 || epoch == epochs {
+			if err := SaveNetwork(t.Network(), fmt.Sprintf("%s/nnue_%d.nnue", checkpointDir, epoch)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Loss returns the mean square error of the network on the dataset, computed in parallel
+func (t *NNUETrainer) Loss(dataset []NNUEEntry) float64 {
+	var wg sync.WaitGroup
+	squareErrors := make([]float64, t.Workers)
+
+	for w := range t.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := w; i < len(dataset); i += t.Workers {
+				eval, _ := t.evaluate(&dataset[i])
+				squareErrors[w] += math.Pow(sigmoid(t.ScalingFactor*eval)-t.target(&dataset[i]), 2)
+			}
+		}()
+	}
+	wg.Wait()
+
+	totalError := 0.0
+	for _, e := range squareErrors {
+		totalError += e
+	}
+	return totalError / float64(len(dataset))
+}
+
+// SaveNetwork writes the network to the file passed, creating its directory when needed
+func SaveNetwork(network *engine.Network, filename string) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return network.Write(file)
+}
+
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
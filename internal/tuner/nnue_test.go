@@ -0,0 +1,115 @@
+package tuner
+
+import (
+	"math"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/gabtar/aconcagua/internal/engine"
+)
+
+// nnueTestDataset returns a small dataset of positions with a clear advantage and their results
+func nnueTestDataset() []DatasetEntry {
+	return []DatasetEntry{
+		{Fen: "rnb1kbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 1", Result: 1.0},
+		{Fen: "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNB1KBNR b KQkq - 0 1", Result: 1.0},
+		{Fen: "5rk1/8/8/8/8/8/1R2K3/8 w - - 0 1", Result: 0.5, Score: 0, HasScore: true},
+		{Fen: "3k4/3p4/8/K1P4r/8/8/8/8 b - - 0 1", Result: 0.0, Score: -450, HasScore: true},
+		{Fen: "8/8/4k3/8/2p5/8/B2P2K1/8 w - - 0 1", Result: 0.5},
+		{Fen: "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", Result: 0.5},
+	}
+}
+
+func TestNNUETrainerEvaluatesLikeTheEngine(t *testing.T) {
+	trainer := NewNNUETrainer(engine.BootstrapNetwork(), 0.0001, 1)
+	dataset := nnueTestDataset()
+	entries := NewNNUEDataset(dataset)
+	ev := engine.NewEvaluation(engine.DefaultPawnHashTableSizeInMb)
+	ev.Network = trainer.Network()
+
+	for i, entry := range dataset {
+		pos := engine.NewPosition()
+		pos.LoadFromFenString(entry.Fen)
+
+		expected := ev.Evaluate(pos)
+		got, _ := trainer.evaluate(&entries[i])
+
+		if int(got) != expected {
+			t.Errorf("Expected: %v, got: %v at %v", expected, got, entry.Fen)
+		}
+	}
+}
+
+func TestNNUETrainingEvaluatesLikeTheQuantizedNetwork(t *testing.T) {
+	trainer := NewNNUETrainer(engine.BootstrapNetwork(), 0.001, 1)
+	trainer.BatchSize, trainer.Workers = 4, 2
+	dataset := nnueTestDataset()
+	entries := NewNNUEDataset(dataset)
+	trainer.Train(slices.Clone(entries), 5, t.TempDir(), 5)
+	ev := engine.NewEvaluation(engine.DefaultPawnHashTableSizeInMb)
+	ev.Network = trainer.Network()
+
+	// The trained params are not on the steps of the quantized network, but the forward pass is
+	for i, entry := range dataset {
+		pos := engine.NewPosition()
+		pos.LoadFromFenString(entry.Fen)
+
+		expected := ev.Evaluate(pos)
+		got, _ := trainer.evaluate(&entries[i])
+
+		if math.Abs(got-float64(expected)) > 1 {
+			t.Errorf("Expected: %v, got: %v at %v", expected, got, entry.Fen)
+		}
+	}
+}
+
+func TestNNUETrainingReducesTheLoss(t *testing.T) {
+	trainer := NewNNUETrainer(engine.BootstrapNetwork(), 0.0001, 1)
+	trainer.BatchSize, trainer.Workers = 4, 2
+	entries := NewNNUEDataset(nnueTestDataset())
+	dir := t.TempDir()
+
+	before := trainer.Loss(entries)
+	err := trainer.Train(entries, 20, dir, 10)
+	after := trainer.Loss(entries)
+
+	if err != nil || after >= before {
+		t.Errorf("Expected: %v, got: %v (%v)", "a lower loss", after, err)
+	}
+
+	checkpoint, err := engine.LoadNetwork(dir + "/nnue_20.nnue")
+	if err != nil || *checkpoint != *trainer.Network() {
+		t.Errorf("Expected: %v, got: %v", "the trained network", err)
+	}
+}
+
+func TestLoadDataSetSkipsTheSpecializedEndgames(t *testing.T) {
+	filename := t.TempDir() + "/dataset.book"
+	data := "5rk1/8/8/8/8/8/1R2K3/8 w - - 0 1 [0.5]\n8/8/8/4k3/8/8/8/KQ6 w - - 0 1 [1.0]\n3k4/3p4/8/K1P4r/8/8/8/8 b - - 0 1 [0.0]\n"
+	os.WriteFile(filename, []byte(data), 0644)
+
+	dataset := LoadDataSet(filename, 3)
+	entries := NewNNUEDataset(dataset)
+
+	expected := 2
+	if len(dataset) != expected || len(entries) != expected {
+		t.Errorf("Expected: %v, got: %v", expected, len(dataset))
+	}
+}
+
+func TestLoadDataSetWithScores(t *testing.T) {
+	filename := t.TempDir() + "/dataset.book"
+	data := "5rk1/8/8/8/8/8/1R2K3/8 w - - 0 1 [0.5]\n3k4/3p4/8/K1P4r/8/8/8/8 b - - 0 1 [0.0] -450\n"
+	os.WriteFile(filename, []byte(data), 0644)
+
+	dataset := LoadDataSet(filename, 2)
+
+	expected := []DatasetEntry{{Result: 0.5}, {Result: 0.0, Score: -450, HasScore: true}}
+	for i, entry := range dataset {
+		got := DatasetEntry{Result: entry.Result, Score: entry.Score, HasScore: entry.HasScore}
+		if got.Result != expected[i].Result || got.Score != expected[i].Score || got.HasScore != expected[i].HasScore {
+			t.Errorf("Expected: %v, got: %v", expected[i], got)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"math"
 	"math/bits"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -20,10 +21,12 @@ const ScalingFactor = 0.008000000000000007 // lichess-big3-resolved
 
 // DatasetEntry is an struct conatining a single training example
 type DatasetEntry struct {
-	Fen     string
-	Result  float64
-	Weights []PositionWeight
-	Phase   int
+	Fen      string
+	Result   float64
+	Score    float64 // Search score of the position from white's side, when HasScore is set
+	HasScore bool
+	Weights  []PositionWeight
+	Phase    int
 }
 
 // NewDataset returns a new preallocated dataset
@@ -78,14 +81,24 @@ func LoadDataSet(filename string, size int) (dataset []DatasetEntry) {
 
 		fen := parts[0]
 		pos.LoadFromFenString(fen)
+
+		// The specialized endgame evaluations don't depend on the params
+		if engine.SpecializedEndgame(pos) {
+			continue
+		}
 		phase := getMiddleGamePhase(pos)
-		result := resultString[parts[1]]
+		resultPart, scorePart, _ := strings.Cut(parts[1], "]")
+		result := resultString[resultPart+"]"]
 
 		generatePositionWeights(pos, phase, &dataset[count].Weights)
 		dataset[count].Fen = fen
 		dataset[count].Result = result
 		dataset[count].Phase = phase
 
+		// Optional search score after the result, like "[1.0] 35"
+		score, err := strconv.ParseFloat(strings.TrimSpace(scorePart), 64)
+		dataset[count].Score, dataset[count].HasScore = score, err == nil
+
 		count++
 		if count >= size {
 			break
@@ -97,11 +110,15 @@ func LoadDataSet(filename string, size int) (dataset []DatasetEntry) {
 		}
 	}
 
-	return dataset
+	// Skipped positions leave the last entries empty
+	return dataset[:count]
 }
 
 // Number of total tuneable params
-const TuneableParams = 987
+const TuneableParams = 1000
+
+// FirstScaleParam is the index of the first endgame scale factor. Scale factors are the last params
+const FirstScaleParam = 996
 
 // GetEvaluationParams returns the current evaluation params
 func GetEvaluationParams() (params [TuneableParams]float64) {
@@ -198,6 +215,20 @@ func GetEvaluationParams() (params [TuneableParams]float64) {
 	// Tempo
 	intParams[986] = engine.TempoBonus
 
+	// Passed pawns dynamic terms
+	intParams[987] = engine.PassedPawnFreePathBonusMg
+	intParams[988] = engine.PassedPawnFreePathBonusEg
+	intParams[989] = engine.PassedPawnAttackedPathPenaltyMg
+	intParams[990] = engine.PassedPawnAttackedPathPenaltyEg
+	intParams[991] = engine.PassedPawnOwnKingDistanceEg
+	intParams[992] = engine.PassedPawnEnemyKingDistanceEg
+	intParams[993] = engine.UnstoppablePassedPawnBonusEg
+	intParams[994] = engine.RookBehindPassedPawnBonusMg
+	intParams[995] = engine.RookBehindPassedPawnBonusEg
+
+	// Endgame scale factors
+	copy(intParams[996:1000], engine.EndgameScales[:])
+
 	// Convert to float
 	for i := range TuneableParams {
 		params[i] = float64(intParams[i])
@@ -344,6 +375,20 @@ func paramsToPrettyFormat(bestParams [TuneableParams]float64) (psqt string) {
 	// Tempo
 	psqt += fmt.Sprintf("TempoBonus: %d\n", intParams[986])
 
+	// Passed pawns dynamic terms
+	psqt += fmt.Sprintf("PassedPawnFreePathBonusMg: %d\n", intParams[987])
+	psqt += fmt.Sprintf("PassedPawnFreePathBonusEg: %d\n", intParams[988])
+	psqt += fmt.Sprintf("PassedPawnAttackedPathPenaltyMg: %d\n", intParams[989])
+	psqt += fmt.Sprintf("PassedPawnAttackedPathPenaltyEg: %d\n", intParams[990])
+	psqt += fmt.Sprintf("PassedPawnOwnKingDistanceEg: %d\n", intParams[991])
+	psqt += fmt.Sprintf("PassedPawnEnemyKingDistanceEg: %d\n", intParams[992])
+	psqt += fmt.Sprintf("UnstoppablePassedPawnBonusEg: %d\n", intParams[993])
+	psqt += fmt.Sprintf("RookBehindPassedPawnBonusMg: %d\n", intParams[994])
+	psqt += fmt.Sprintf("RookBehindPassedPawnBonusEg: %d\n", intParams[995])
+
+	// Endgame scale factors
+	psqt += fmt.Sprintf("EndgameScales: %#v\n", intParams[996:1000])
+
 	return psqt
 }
 
@@ -417,34 +462,63 @@ type PositionWeight struct {
 
 // evaluatePosition returns the static evaluation of a position based on the weights and current params
 func evaluatePosition(params *[TuneableParams]float64, weights *[]PositionWeight) (evaluation float64) {
-	eval := 0.0
+	eval, scale := 0.0, 1.0
 	for i := range len(*weights) {
 		idx := (*weights)[i].paramIndex
 		weight := (*weights)[i].weight
 
-		eval += (*params)[idx] * float64(weight)
+		// The weights after a scale factor are multiplied by it
+		if idx >= FirstScaleParam {
+			scale = (*params)[idx] / engine.ScaleNormal
+			continue
+		}
+		eval += (*params)[idx] * float64(weight) * scale
 	}
 	evaluation = eval / 62
 	return
 }
 
-// generatePositionWeights returns all the position weights of a position
+// generatePositionWeights returns all the position weights of a position. On the drawish endings, the endgame
+// weights follow the weight of the scale factor, which multiplies them
 func generatePositionWeights(pos *engine.Position, phase int, weights *[]PositionWeight) {
-	generatePieceScoreWeights(pos, phase, weights)
-	generateMobilityWeights(pos, phase, weights)
-	generatePawnsStructureWeights(pos, phase, weights)
-	generateMaterialAdjustmentsWeights(pos, phase, weights)
-	generateKingSafetyWeights(pos, phase, weights)
+	term := engine.ScaleTerm(pos)
+	if term == engine.NoScaleTerm {
+		generateTermsWeights(pos, phase, 62-phase, weights)
+		return
+	}
+
+	generateTermsWeights(pos, phase, 0, weights)
+	*weights = append(*weights, PositionWeight{paramIndex: int16(FirstScaleParam + term)})
+	generateTermsWeights(pos, 0, 62-phase, weights)
+
+	// Remove the empty weights of each phase
+	nonZero := (*weights)[:0]
+	for _, weight := range *weights {
+		if weight.weight != 0 || weight.paramIndex >= FirstScaleParam {
+			nonZero = append(nonZero, weight)
+		}
+	}
+	*weights = nonZero
+}
+
+// generateTermsWeights returns the position weights of the evaluation terms, with the middlegame and endgame phases
+func generateTermsWeights(pos *engine.Position, phase, egPhase int, weights *[]PositionWeight) {
+	generatePieceScoreWeights(pos, phase, egPhase, weights)
+	generateMobilityWeights(pos, phase, egPhase, weights)
+	generatePawnsStructureWeights(pos, phase, egPhase, weights)
+	generatePassedPawnsWeights(pos, phase, egPhase, weights)
+	generateMaterialAdjustmentsWeights(pos, phase, egPhase, weights)
+	generateKingSafetyWeights(pos, phase, egPhase, weights)
 
 	// Tempo Bonus weight
 	*weights = append(*weights,
 		PositionWeight{paramIndex: 986, weight: int16(pos.Turn.Modifier() * phase)},
-		PositionWeight{paramIndex: 986, weight: int16(pos.Turn.Modifier() * (62 - phase))},
+		PositionWeight{paramIndex: 986, weight: int16(pos.Turn.Modifier() * egPhase)},
 	)
 }
 
 // generatePieceScoreWeights returns the weights of the pieces socre in the board
-func generatePieceScoreWeights(pos *engine.Position, phase int, weights *[]PositionWeight) {
+func generatePieceScoreWeights(pos *engine.Position, phase, egPhase int, weights *[]PositionWeight) {
 	for piece, bb := range pos.Pieces {
 		side := engine.Color(piece / 6)
 		for bb > 0 {
@@ -455,16 +529,16 @@ func generatePieceScoreWeights(pos *engine.Position, phase int, weights *[]Posit
 
 			*weights = append(*weights,
 				PositionWeight{paramIndex: int16(768 + piece%6), weight: int16(side.Modifier() * phase)},
-				PositionWeight{paramIndex: int16(768 + piece%6 + 6), weight: int16(side.Modifier() * (62 - phase))},
+				PositionWeight{paramIndex: int16(768 + piece%6 + 6), weight: int16(side.Modifier() * egPhase)},
 				PositionWeight{paramIndex: int16((piece%6)*64 + sq), weight: int16(side.Modifier() * phase)},
-				PositionWeight{paramIndex: int16(384 + (piece%6)*64 + sq), weight: int16(side.Modifier() * (62 - phase))},
+				PositionWeight{paramIndex: int16(384 + (piece%6)*64 + sq), weight: int16(side.Modifier() * egPhase)},
 			)
 		}
 	}
 }
 
 // generateMobilityWeights returns the position weithts of the mobility of the position
-func generateMobilityWeights(pos *engine.Position, phase int, weights *[]PositionWeight) {
+func generateMobilityWeights(pos *engine.Position, phase, egPhase int, weights *[]PositionWeight) {
 	var pieces = [8]int{engine.WhiteQueen, engine.WhiteRook, engine.WhiteBishop, engine.WhiteKnight, engine.BlackQueen, engine.BlackRook, engine.BlackBishop, engine.BlackKnight}
 
 	// Start indexes for mobility arrays (Queen, rook, bishop, knight)
@@ -494,7 +568,7 @@ func generateMobilityWeights(pos *engine.Position, phase int, weights *[]Positio
 
 			*weights = append(*weights,
 				PositionWeight{paramIndex: int16(mgIndexes[piece%4] + safeSquares), weight: int16(side.Modifier() * phase)},
-				PositionWeight{paramIndex: int16(egIndexes[piece%4] + safeSquares), weight: int16(side.Modifier() * (62 - phase))},
+				PositionWeight{paramIndex: int16(egIndexes[piece%4] + safeSquares), weight: int16(side.Modifier() * egPhase)},
 			)
 
 			// Threats
@@ -502,7 +576,7 @@ func generateMobilityWeights(pos *engine.Position, phase int, weights *[]Positio
 				if enemyPawnsAttacks[piece/4]&fromBB > 0 {
 					*weights = append(*weights,
 						PositionWeight{paramIndex: 969, weight: int16(side.Modifier() * phase)},
-						PositionWeight{paramIndex: 969, weight: int16(side.Modifier() * (62 - phase))},
+						PositionWeight{paramIndex: 969, weight: int16(side.Modifier() * egPhase)},
 					)
 				}
 
@@ -510,7 +584,7 @@ func generateMobilityWeights(pos *engine.Position, phase int, weights *[]Positio
 				if attacks&enemyRooks > 0 {
 					*weights = append(*weights,
 						PositionWeight{paramIndex: 972, weight: int16(int(side) * phase)},
-						PositionWeight{paramIndex: 972, weight: int16(int(side) * (62 - phase))},
+						PositionWeight{paramIndex: 972, weight: int16(int(side) * egPhase)},
 					)
 				}
 
@@ -518,7 +592,7 @@ func generateMobilityWeights(pos *engine.Position, phase int, weights *[]Positio
 				if attacks&enemyQueens > 0 {
 					*weights = append(*weights,
 						PositionWeight{paramIndex: 973, weight: int16(int(side) * phase)},
-						PositionWeight{paramIndex: 973, weight: int16(int(side) * (62 - phase))},
+						PositionWeight{paramIndex: 973, weight: int16(int(side) * egPhase)},
 					)
 				}
 
@@ -532,7 +606,7 @@ func generateMobilityWeights(pos *engine.Position, phase int, weights *[]Positio
 				if safeChecks > 0 {
 					*weights = append(*weights,
 						PositionWeight{paramIndex: safeCheckIndex, weight: int16(side.Modifier() * safeChecksCount * phase)},
-						PositionWeight{paramIndex: safeCheckIndex, weight: int16(side.Modifier() * safeChecksCount * (62 - phase))},
+						PositionWeight{paramIndex: safeCheckIndex, weight: int16(side.Modifier() * safeChecksCount * egPhase)},
 					)
 				}
 			}
@@ -541,7 +615,7 @@ func generateMobilityWeights(pos *engine.Position, phase int, weights *[]Positio
 				if enemyPawnsAttacks[piece/4]&fromBB > 0 {
 					*weights = append(*weights,
 						PositionWeight{paramIndex: 970, weight: int16(side.Modifier() * phase)},
-						PositionWeight{paramIndex: 970, weight: int16(side.Modifier() * (62 - phase))},
+						PositionWeight{paramIndex: 970, weight: int16(side.Modifier() * egPhase)},
 					)
 				}
 
@@ -550,7 +624,7 @@ func generateMobilityWeights(pos *engine.Position, phase int, weights *[]Positio
 				if safeChecks > 0 {
 					*weights = append(*weights,
 						PositionWeight{paramIndex: 983, weight: int16(side.Modifier() * safeChecksCount * phase)},
-						PositionWeight{paramIndex: 983, weight: int16(side.Modifier() * safeChecksCount * (62 - phase))},
+						PositionWeight{paramIndex: 983, weight: int16(side.Modifier() * safeChecksCount * egPhase)},
 					)
 				}
 			}
@@ -559,7 +633,7 @@ func generateMobilityWeights(pos *engine.Position, phase int, weights *[]Positio
 				if enemyPawnsAttacks[piece/4]&fromBB > 0 {
 					*weights = append(*weights,
 						PositionWeight{paramIndex: 971, weight: int16(side.Modifier() * phase)},
-						PositionWeight{paramIndex: 971, weight: int16(side.Modifier() * (62 - phase))},
+						PositionWeight{paramIndex: 971, weight: int16(side.Modifier() * egPhase)},
 					)
 				}
 
@@ -568,7 +642,7 @@ func generateMobilityWeights(pos *engine.Position, phase int, weights *[]Positio
 				if safeChecks > 0 {
 					*weights = append(*weights,
 						PositionWeight{paramIndex: 982, weight: int16(side.Modifier() * safeChecksCount * phase)},
-						PositionWeight{paramIndex: 982, weight: int16(side.Modifier() * safeChecksCount * (62 - phase))},
+						PositionWeight{paramIndex: 982, weight: int16(side.Modifier() * safeChecksCount * egPhase)},
 					)
 				}
 			}
@@ -578,25 +652,25 @@ func generateMobilityWeights(pos *engine.Position, phase int, weights *[]Positio
 				if pieces[piece]%6 == engine.Queen {
 					*weights = append(*weights,
 						PositionWeight{paramIndex: 974, weight: int16(side.Modifier() * phase)},
-						PositionWeight{paramIndex: 978, weight: int16(side.Modifier() * (62 - phase))},
+						PositionWeight{paramIndex: 978, weight: int16(side.Modifier() * egPhase)},
 					)
 				}
 				if pieces[piece]%6 == engine.Rook {
 					*weights = append(*weights,
 						PositionWeight{paramIndex: 975, weight: int16(side.Modifier() * phase)},
-						PositionWeight{paramIndex: 979, weight: int16(side.Modifier() * (62 - phase))},
+						PositionWeight{paramIndex: 979, weight: int16(side.Modifier() * egPhase)},
 					)
 				}
 				if pieces[piece]%6 == engine.Bishop {
 					*weights = append(*weights,
 						PositionWeight{paramIndex: 976, weight: int16(side.Modifier() * phase)},
-						PositionWeight{paramIndex: 980, weight: int16(side.Modifier() * (62 - phase))},
+						PositionWeight{paramIndex: 980, weight: int16(side.Modifier() * egPhase)},
 					)
 				}
 				if pieces[piece]%6 == engine.Knight {
 					*weights = append(*weights,
 						PositionWeight{paramIndex: 977, weight: int16(side.Modifier() * phase)},
-						PositionWeight{paramIndex: 981, weight: int16(side.Modifier() * (62 - phase))},
+						PositionWeight{paramIndex: 981, weight: int16(side.Modifier() * egPhase)},
 					)
 				}
 			}
@@ -605,7 +679,7 @@ func generateMobilityWeights(pos *engine.Position, phase int, weights *[]Positio
 }
 
 // generatePawnsStructureWeights generates the poosition weights of pawn structure analysis
-func generatePawnsStructureWeights(pos *engine.Position, phase int, weights *[]PositionWeight) {
+func generatePawnsStructureWeights(pos *engine.Position, phase, egPhase int, weights *[]PositionWeight) {
 	for side := engine.Color(engine.White); side <= engine.Black; side++ {
 		piece := engine.WhitePawn + 6*int(side)
 		pawns := pos.Pieces[piece]
@@ -628,7 +702,7 @@ func generatePawnsStructureWeights(pos *engine.Position, phase int, weights *[]P
 			if bits.OnesCount64(uint64(pawnsInFile)) > 1 {
 				*weights = append(*weights,
 					PositionWeight{paramIndex: 912, weight: int16(side.Modifier() * phase)},
-					PositionWeight{paramIndex: 913, weight: int16(side.Modifier() * (62 - phase))},
+					PositionWeight{paramIndex: 913, weight: int16(side.Modifier() * egPhase)},
 				)
 			}
 
@@ -636,7 +710,7 @@ func generatePawnsStructureWeights(pos *engine.Position, phase int, weights *[]P
 			if engine.IsolatedAdjacentFilesMask[file]&pawns == 0 {
 				*weights = append(*weights,
 					PositionWeight{paramIndex: 914, weight: int16(side.Modifier() * phase)},
-					PositionWeight{paramIndex: 915, weight: int16(side.Modifier() * (62 - phase))},
+					PositionWeight{paramIndex: 915, weight: int16(side.Modifier() * egPhase)},
 				)
 			}
 
@@ -645,7 +719,7 @@ func generatePawnsStructureWeights(pos *engine.Position, phase int, weights *[]P
 			if backward {
 				*weights = append(*weights,
 					PositionWeight{paramIndex: 916, weight: int16(side.Modifier() * phase)},
-					PositionWeight{paramIndex: 917, weight: int16(side.Modifier() * (62 - phase))},
+					PositionWeight{paramIndex: 917, weight: int16(side.Modifier() * egPhase)},
 				)
 			}
 
@@ -657,7 +731,7 @@ func generatePawnsStructureWeights(pos *engine.Position, phase int, weights *[]P
 				}
 				*weights = append(*weights,
 					PositionWeight{paramIndex: int16(922 + rank), weight: int16(side.Modifier() * phase)},
-					PositionWeight{paramIndex: int16(930 + rank), weight: int16(side.Modifier() * (62 - phase))},
+					PositionWeight{paramIndex: int16(930 + rank), weight: int16(side.Modifier() * egPhase)},
 				)
 			}
 
@@ -666,7 +740,7 @@ func generatePawnsStructureWeights(pos *engine.Position, phase int, weights *[]P
 			if defenders > 0 {
 				*weights = append(*weights,
 					PositionWeight{paramIndex: 918, weight: int16(side.Modifier() * defenders * phase)},
-					PositionWeight{paramIndex: 919, weight: int16(side.Modifier() * defenders * (62 - phase))},
+					PositionWeight{paramIndex: 919, weight: int16(side.Modifier() * defenders * egPhase)},
 				)
 			}
 
@@ -675,7 +749,80 @@ func generatePawnsStructureWeights(pos *engine.Position, phase int, weights *[]P
 			if !backward && connected > 0 {
 				*weights = append(*weights,
 					PositionWeight{paramIndex: 920, weight: int16(side.Modifier() * connected * phase)},
-					PositionWeight{paramIndex: 921, weight: int16(side.Modifier() * connected * (62 - phase))},
+					PositionWeight{paramIndex: 921, weight: int16(side.Modifier() * connected * egPhase)},
+				)
+			}
+		}
+	}
+}
+
+// generatePassedPawnsWeights returns the position weights of the passed pawns terms that depend on the pieces
+func generatePassedPawnsWeights(pos *engine.Position, phase, egPhase int, weights *[]PositionWeight) {
+	forward := [2]int{engine.North, engine.South}
+	backward := [2]int{engine.South, engine.North}
+	blocks := pos.Sides[engine.All]
+
+	for side := engine.Color(engine.White); side <= engine.Black; side++ {
+		opponent := side.Opponent()
+		pawn, enemyPawn := engine.WhitePawn+6*int(side), engine.WhitePawn+6*int(opponent)
+		rook, enemyKing := engine.WhiteRook+6*int(side), pos.KingPosition(opponent)
+		passedPawns := engine.PassedPawns(pos.Pieces[pawn], pos.Pieces[enemyPawn], side)
+		enemyAttacks := engine.AttackedSquares(pos, opponent)
+		loneKing := pos.Sides[opponent] == enemyKing|pos.Pieces[enemyPawn]
+
+		for passedPawns > 0 {
+			fromBB := passedPawns.NextBit()
+			from := engine.Bsf(fromBB)
+			rank := from / 8
+			if side == engine.Black {
+				rank = 7 - rank
+			}
+			path := engine.RayAttacks[forward[side]][from]
+			weight := side.Modifier() * engine.PassedPawnWeight(rank)
+
+			// Unstoppable
+			if loneKing && path&blocks == 0 {
+				promotion := engine.Bsf(path & (engine.Ranks[0] | engine.Ranks[7]))
+				kingDistance := engine.SquareDistance(engine.Bsf(enemyKing), promotion)
+				if pos.Turn == opponent {
+					kingDistance--
+				}
+				if min(7-rank, 5) < kingDistance {
+					*weights = append(*weights,
+						PositionWeight{paramIndex: 993, weight: int16(side.Modifier() * egPhase)},
+					)
+				}
+			}
+
+			// Free and attacked path
+			if path&blocks == 0 {
+				*weights = append(*weights,
+					PositionWeight{paramIndex: 987, weight: int16(weight * phase)},
+					PositionWeight{paramIndex: 988, weight: int16(weight * egPhase)},
+				)
+			}
+			if path&enemyAttacks > 0 {
+				*weights = append(*weights,
+					PositionWeight{paramIndex: 989, weight: int16(weight * phase)},
+					PositionWeight{paramIndex: 990, weight: int16(weight * egPhase)},
+				)
+			}
+
+			// Kings proximity to the stop square
+			stop := engine.Bsf(path & (fromBB<<8 | fromBB>>8))
+			ownDistance := engine.SquareDistance(engine.Bsf(pos.KingPosition(side)), stop)
+			enemyDistance := engine.SquareDistance(engine.Bsf(enemyKing), stop)
+			*weights = append(*weights,
+				PositionWeight{paramIndex: 991, weight: int16(weight * ownDistance * egPhase)},
+				PositionWeight{paramIndex: 992, weight: int16(weight * enemyDistance * egPhase)},
+			)
+
+			// Rook behind
+			rookAttacks := engine.Attacks(rook, fromBB, blocks) & engine.RayAttacks[backward[side]][from]
+			if rookAttacks&pos.Pieces[rook] > 0 {
+				*weights = append(*weights,
+					PositionWeight{paramIndex: 994, weight: int16(side.Modifier() * phase)},
+					PositionWeight{paramIndex: 995, weight: int16(side.Modifier() * egPhase)},
 				)
 			}
 		}
@@ -683,32 +830,32 @@ func generatePawnsStructureWeights(pos *engine.Position, phase int, weights *[]P
 }
 
 // generateMaterialAdjustmentsWeights returns the position weights of the material adjustments
-func generateMaterialAdjustmentsWeights(pos *engine.Position, phase int, weights *[]PositionWeight) {
+func generateMaterialAdjustmentsWeights(pos *engine.Position, phase, egPhase int, weights *[]PositionWeight) {
 	// Bishop pairs bonuses
 	whiteBishopCount := bits.OnesCount64(uint64(pos.Pieces[engine.WhiteBishop]))
 	if whiteBishopCount >= 2 {
 		*weights = append(*weights,
 			PositionWeight{paramIndex: 938, weight: int16(phase)},
-			PositionWeight{paramIndex: 939, weight: int16(62 - phase)},
+			PositionWeight{paramIndex: 939, weight: int16(egPhase)},
 		)
 	}
 	blackBishopCount := bits.OnesCount64(uint64(pos.Pieces[engine.BlackBishop]))
 	if blackBishopCount >= 2 {
 		*weights = append(*weights,
 			PositionWeight{paramIndex: 938, weight: int16(-phase)},
-			PositionWeight{paramIndex: 939, weight: int16(-(62 - phase))},
+			PositionWeight{paramIndex: 939, weight: int16(-egPhase)},
 		)
 	}
 
-	generateOutpostWeights(pos, phase, weights)
+	generateOutpostWeights(pos, phase, egPhase, weights)
 
 	// Rook on Open/SemiOpenFile
-	generateRookOnOpenFileWeights(pos, phase, weights, engine.White)
-	generateRookOnOpenFileWeights(pos, phase, weights, engine.Black)
+	generateRookOnOpenFileWeights(pos, phase, egPhase, weights, engine.White)
+	generateRookOnOpenFileWeights(pos, phase, egPhase, weights, engine.Black)
 }
 
 // generateRookOnOpenFileWeights returns the position weights of the rook on open file
-func generateRookOnOpenFileWeights(pos *engine.Position, phase int, weights *[]PositionWeight, side engine.Color) {
+func generateRookOnOpenFileWeights(pos *engine.Position, phase, egPhase int, weights *[]PositionWeight, side engine.Color) {
 	alliedPawns := pos.Pieces[engine.Pawn+int(side)*6]
 	enemyPawns := pos.Pieces[engine.Pawn+int(side.Opponent())*6]
 
@@ -732,7 +879,7 @@ func generateRookOnOpenFileWeights(pos *engine.Position, phase int, weights *[]P
 }
 
 // generateOutpostWeights returns the position weights of the outpost
-func generateOutpostWeights(pos *engine.Position, phase int, weights *[]PositionWeight) {
+func generateOutpostWeights(pos *engine.Position, phase, egPhase int, weights *[]PositionWeight) {
 	outpostSquares := [2]engine.Bitboard{
 		engine.OutpostSquares(pos.Pieces[engine.WhitePawn], pos.Pieces[engine.BlackPawn], engine.White),
 		engine.OutpostSquares(pos.Pieces[engine.BlackPawn], pos.Pieces[engine.WhitePawn], engine.Black),
@@ -746,7 +893,7 @@ func generateOutpostWeights(pos *engine.Position, phase int, weights *[]Position
 		if outpostSquares[engine.White]&fromBB > 0 {
 			*weights = append(*weights,
 				PositionWeight{paramIndex: 942, weight: int16(phase)},
-				PositionWeight{paramIndex: 943, weight: int16(62 - phase)},
+				PositionWeight{paramIndex: 943, weight: int16(egPhase)},
 			)
 		}
 	}
@@ -758,7 +905,7 @@ func generateOutpostWeights(pos *engine.Position, phase int, weights *[]Position
 		if outpostSquares[engine.Black]&fromBB > 0 {
 			*weights = append(*weights,
 				PositionWeight{paramIndex: 942, weight: int16(-phase)},
-				PositionWeight{paramIndex: 943, weight: int16(-(62 - phase))},
+				PositionWeight{paramIndex: 943, weight: int16(-egPhase)},
 			)
 		}
 	}
@@ -771,7 +918,7 @@ func generateOutpostWeights(pos *engine.Position, phase int, weights *[]Position
 		if outpostSquares[engine.White]&fromBB > 0 {
 			*weights = append(*weights,
 				PositionWeight{paramIndex: 944, weight: int16(phase)},
-				PositionWeight{paramIndex: 945, weight: int16(62 - phase)},
+				PositionWeight{paramIndex: 945, weight: int16(egPhase)},
 			)
 		}
 	}
@@ -783,20 +930,20 @@ func generateOutpostWeights(pos *engine.Position, phase int, weights *[]Position
 		if outpostSquares[engine.Black]&fromBB > 0 {
 			*weights = append(*weights,
 				PositionWeight{paramIndex: 944, weight: int16(-phase)},
-				PositionWeight{paramIndex: 945, weight: int16(-(62 - phase))},
+				PositionWeight{paramIndex: 945, weight: int16(-egPhase)},
 			)
 		}
 	}
 }
 
 // generateKingSafetyWeights returns the position weights of the king safety
-func generateKingSafetyWeights(pos *engine.Position, phase int, weights *[]PositionWeight) {
-	generateSafetyAttacksWeights(pos, phase, weights)
-	generatePawnShieldAndStormWeights(pos, phase, weights)
+func generateKingSafetyWeights(pos *engine.Position, phase, egPhase int, weights *[]PositionWeight) {
+	generateSafetyAttacksWeights(pos, phase, egPhase, weights)
+	generatePawnShieldAndStormWeights(pos, phase, egPhase, weights)
 }
 
 // generateSafetyAttacksWeights returns the attacks weights of the position for king safety evaluation
-func generateSafetyAttacksWeights(pos *engine.Position, phase int, weights *[]PositionWeight) {
+func generateSafetyAttacksWeights(pos *engine.Position, phase, egPhase int, weights *[]PositionWeight) {
 	blocks := ^pos.EmptySquares()
 
 	for color := engine.White; color <= engine.Black; color++ {
@@ -837,7 +984,7 @@ func generateSafetyAttacksWeights(pos *engine.Position, phase int, weights *[]Po
 }
 
 // generatePawnShieldAndStormWeights returns the position weights of the pawn shield and storm
-func generatePawnShieldAndStormWeights(pos *engine.Position, phase int, weights *[]PositionWeight) {
+func generatePawnShieldAndStormWeights(pos *engine.Position, phase, egPhase int, weights *[]PositionWeight) {
 	pawns := [2]engine.Bitboard{
 		pos.Pieces[engine.WhitePawn],
 		pos.Pieces[engine.BlackPawn],
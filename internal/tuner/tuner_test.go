@@ -35,12 +35,22 @@ func TestEvaluation(t *testing.T) {
 		{"Eval 22", "2k5/8/8/8/8/4K3/8/8 w - - 0 1"},
 		{"Eval 23", "4r1k1/5ppp/2N5/3Pb3/8/6P1/5P1P/4R1K1 w - - 0 1"}, // Outpost test
 		{"Eval 24", "8/2b2k2/5pp1/3N4/6PP/4QPK1/2q2P2/8 w - - 0 1"},   // New Safety test
+		{"Eval 25", "8/5k2/4bp2/8/3B4/5P2/5K2/8 w - - 0 1"},           // Opposite colored bishops
+		{"Eval 26", "8/8/3k4/8/2b5/8/4R3/4K3 b - - 0 1"},              // Rook against bishop
+		{"Eval 27", "k7/8/1K6/P7/8/8/8/2B5 w - - 0 1"},                // Wrong colored bishop
+		{"Eval 28", "8/3k4/8/3n4/8/3N4/2BK4/8 w - - 0 1"},             // Pawnless minor piece edge
+		{"Eval 29", "8/6k1/8/1P6/8/8/5PPK/8 w - - 0 1"},               // Unstoppable passed pawn
+		{"Eval 30", "3r2k1/5ppp/8/3P4/8/8/5PPP/3R2K1 w - - 0 1"},      // Rook behind a passed pawn
+		{"Eval 31", "8/5k2/8/8/3p4/8/2K2PP1/8 b - - 0 1"},             // Black passed pawn and kings proximity
 	}
 
 	for _, tc := range testCases {
 		pos := engine.NewPosition()
 		t.Run(tc.name, func(t *testing.T) {
 			pos.LoadFromFenString(tc.fen)
+			if engine.SpecializedEndgame(pos) {
+				t.Skip("Specialized endgame evaluations are not tuned")
+			}
 			ev := engine.NewEvaluation(engine.DefaultPawnHashTableSizeInMb)
 			staticEval := ev.Evaluate(pos)
 			params := GetEvaluationParams()
@@ -0,0 +1,117 @@
+package tuner
+
+import (
+	"math"
+
+	"github.com/gabtar/aconcagua/internal/engine"
+)
+
+const (
+	winRateBuckets    = 8   // Number of game phase buckets used to fit the win rate model
+	winRateMinSamples = 100 // Min number of positions on a bucket to be used in the fit
+)
+
+// FitWinRateModel fits the win rate model used for the UCI_ShowWDL output to the results of the dataset.
+// Positions are grouped by game phase, and for each group the parameters a and b of the win rate are found.
+// Then a and b are fitted as a linear function of the game phase, weighted by the positions on each group
+func FitWinRateModel(dataset []DatasetEntry, params [TuneableParams]float64) (model engine.WinRateModel) {
+	buckets := make([][]DatasetEntry, winRateBuckets)
+	for _, entry := range dataset {
+		bucket := min(entry.Phase*winRateBuckets/63, winRateBuckets-1)
+		buckets[bucket] = append(buckets[bucket], entry)
+	}
+
+	phases, as, bs, samples := []float64{}, []float64{}, []float64{}, []float64{}
+	for _, bucket := range buckets {
+		if len(bucket) < winRateMinSamples {
+			continue
+		}
+		a, b := fitWinRate(bucket, params)
+
+		phase := 0.0
+		for _, entry := range bucket {
+			phase += float64(entry.Phase)
+		}
+		phases = append(phases, phase/float64(len(bucket)))
+		as = append(as, a)
+		bs = append(bs, b)
+		samples = append(samples, float64(len(bucket)))
+	}
+
+	model.A[0], model.A[1] = linearFit(phases, as, samples)
+	model.B[0], model.B[1] = linearFit(phases, bs, samples)
+	return
+}
+
+// fitWinRate returns the parameters a and b of the win rate that minimize the mean square error of the win and
+// loss predictions. The optimal scaling factor is used as first estimate for b, as without draws (a = 0) the win
+// rate is the same sigmoid used by the tuner. The best parameters are found with a grid search refined on each round
+func fitWinRate(dataset []DatasetEntry, params [TuneableParams]float64) (bestA float64, bestB float64) {
+	evals := make([]float64, len(dataset))
+	for i := range dataset {
+		evals[i] = evaluatePosition(&params, &dataset[i].Weights)
+	}
+
+	b0 := 1 / FindOptimalScalingFactor(dataset, params)
+	aMin, aMax, bMin, bMax := 0.0, 400.0, b0/4, b0*2
+	bestError := math.Inf(1)
+
+	for range 3 {
+		aStep, bStep := (aMax-aMin)/10, (bMax-bMin)/10
+		for a := aMin; a <= aMax+aStep/2; a += aStep {
+			for b := bMin; b <= bMax+bStep/2; b += bStep {
+				if err := winRateError(a, b, evals, dataset); err < bestError {
+					bestError, bestA, bestB = err, a, b
+				}
+			}
+		}
+		aMin, aMax = max(bestA-aStep, 0), bestA+aStep
+		bMin, bMax = max(bestB-bStep, bStep/10), bestB+bStep
+	}
+
+	return
+}
+
+// winRateError returns the mean square error of the win and loss rates predicted for the dataset
+func winRateError(a float64, b float64, evals []float64, dataset []DatasetEntry) float64 {
+	totalError := 0.0
+	for i, entry := range dataset {
+		win := 1 / (1 + math.Exp((a-evals[i])/b))
+		loss := 1 / (1 + math.Exp((a+evals[i])/b))
+
+		isWin, isLoss := 0.0, 0.0
+		switch entry.Result {
+		case 1.0:
+			isWin = 1.0
+		case 0.0:
+			isLoss = 1.0
+		}
+		totalError += (win-isWin)*(win-isWin) + (loss-isLoss)*(loss-isLoss)
+	}
+
+	return totalError / float64(len(dataset))
+}
+
+// linearFit returns the intercept and slope of the weighted least squares line fitting the points
+func linearFit(x []float64, y []float64, weights []float64) (intercept float64, slope float64) {
+	sw, sx, sy, sxx, sxy := 0.0, 0.0, 0.0, 0.0, 0.0
+	for i := range x {
+		sw += weights[i]
+		sx += weights[i] * x[i]
+		sy += weights[i] * y[i]
+		sxx += weights[i] * x[i] * x[i]
+		sxy += weights[i] * x[i] * y[i]
+	}
+	if sw == 0 {
+		return 0, 0
+	}
+
+	denominator := sw*sxx - sx*sx
+	if denominator == 0 {
+		return sy / sw, 0
+	}
+
+	slope = (sw*sxy - sx*sy) / denominator
+	intercept = (sy - slope*sx) / sw
+	return
+}
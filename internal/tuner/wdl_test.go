@@ -0,0 +1,41 @@
+package tuner
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gabtar/aconcagua/internal/engine"
+)
+
+func TestFitWinRateModel(t *testing.T) {
+	expected := engine.WinRateModel{A: [2]float64{100, 1}, B: [2]float64{60, 0.5}}
+	params := [TuneableParams]float64{1}
+
+	// Dataset with the results distribution of the expected model
+	dataset := []DatasetEntry{}
+	for _, phase := range []int{8, 24, 40, 56} {
+		a := expected.A[0] + expected.A[1]*float64(phase)
+		b := expected.B[0] + expected.B[1]*float64(phase)
+		for eval := -500; eval <= 500; eval += 25 {
+			wins := int(math.Round(100 / (1 + math.Exp((a-float64(eval))/b))))
+			losses := int(math.Round(100 / (1 + math.Exp((a+float64(eval))/b))))
+			for i := range 100 {
+				result := 0.5
+				if i < wins {
+					result = 1.0
+				} else if i >= 100-losses {
+					result = 0.0
+				}
+				weights := []PositionWeight{{paramIndex: 0, weight: int16(eval * 62)}}
+				dataset = append(dataset, DatasetEntry{Result: result, Weights: weights, Phase: phase})
+			}
+		}
+	}
+
+	got := FitWinRateModel(dataset, params)
+
+	if math.Abs(got.A[0]-expected.A[0]) > 10 || math.Abs(got.A[1]-expected.A[1]) > 0.2 ||
+		math.Abs(got.B[0]-expected.B[0]) > 10 || math.Abs(got.B[1]-expected.B[1]) > 0.2 {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
@@ -1,8 +1,10 @@
 package uci
 
 import (
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gabtar/aconcagua/internal/engine"
 )
@@ -11,6 +13,10 @@ const (
 	// Allowed hash sizes in MB
 	MaxHashSize = 1024
 	MinHashSize = 1
+
+	// Allowed number of search threads
+	MaxThreads = engine.MaxThreads
+	MinThreads = 1
 )
 
 // UciCommand defines the interface for all UCI commands.
@@ -29,9 +35,21 @@ func (c *UciCommandStruct) Execute(en *engine.Engine, stdout chan string, params
 	stdout <- "option name BookPath type string default <empty>"
 	stdout <- "option name UseBook type check default false"
 	stdout <- "option name UCI_Chess960 type check default false"
+	stdout <- "option name Ponder type check default false"
 	stdout <- "option name Hash type spin default 64 min " + strconv.Itoa(MinHashSize) + " max " + strconv.Itoa(MaxHashSize)
 	stdout <- "option name ClearHash type button"
+	stdout <- "option name MultiPV type spin default 1 min 1 max " + strconv.Itoa(engine.MaxLegalMoves)
+	stdout <- "option name Threads type spin default 1 min " + strconv.Itoa(MinThreads) + " max " + strconv.Itoa(MaxThreads)
+	stdout <- "option name UCI_LimitStrength type check default false"
+	stdout <- "option name UCI_Elo type spin default " + strconv.Itoa(engine.MaxElo) + " min " + strconv.Itoa(engine.MinElo) + " max " + strconv.Itoa(engine.MaxElo)
+	stdout <- "option name UCI_ShowWDL type check default false"
+	stdout <- "option name Seed type spin default 0 min 0 max 2147483647"
 	stdout <- "option name Overhead type spin default 10 min 0 max 1000"
+	stdout <- "option name SyzygyPath type string default <empty>"
+	stdout <- "option name SyzygyProbeDepth type spin default " + strconv.Itoa(engine.DefaultSyzygyProbeDepth) + " min 1 max " + strconv.Itoa(engine.MaxSearchDepth)
+	stdout <- "option name EvalFile type string default <empty>"
+	stdout <- "option name UseNNUE type check default false"
+	stdout <- "option name QuiescentChecks type check default false"
 	stdout <- "uciok"
 }
 
@@ -82,9 +100,20 @@ type UciGoCommandStruct struct{}
 
 // Execute handles the "go" command logic
 func (c *UciGoCommandStruct) Execute(en *engine.Engine, stdout chan string, params ...string) {
-	if en.Options.UseOpeningBook {
-		polyglotEntry := en.OpeningBook.PickRandomOpeningVariation(engine.PolyglotHashFromPosition(&en.Pos))
+	if en.Controller.Thinking() {
+		stdout <- "info string Error: " + engine.ErrSearchRunning.Error()
+		return
+	}
+	ponder := findParam(params, "ponder") != -1
+
+	// The best move can't be sent while pondering, so we skip the book
+	if en.Options.UseOpeningBook && !ponder {
+		polyglotHash := engine.PolyglotHashFromPosition(&en.Pos)
+		polyglotEntry := en.OpeningBook.PickRandomOpeningVariation(polyglotHash)
 		polyglotMove := engine.PolyglotMove(polyglotEntry.Move)
+		if en.Search.Debug {
+			stdout <- "info string book lookup key " + strconv.FormatUint(polyglotHash, 16) + " move " + polyglotMove.String()
+		}
 
 		if polyglotMove != engine.PolyglotMove(0) {
 			stdout <- "bestmove " + polyglotMove.String()
@@ -100,18 +129,44 @@ func (c *UciGoCommandStruct) Execute(en *engine.Engine, stdout chan string, para
 	binc := findParam(params, "binc")
 	movetime := findParam(params, "movetime")
 	movesToGo := findParam(params, "movestogo")
+	nodes := findParam(params, "nodes")
+	mate := findParam(params, "mate")
+
+	en.Search.SetSearchMoves(&en.Pos, searchMoves(params))
 
-	searchStrategy, clock := engine.TimeStrategy(params, depth, wtime, btime, winc, binc, movetime, movesToGo)
+	searchStrategy, clock := engine.TimeStrategy(params, depth, wtime, btime, winc, binc, movetime, movesToGo, nodes, mate)
 	en.Search.TimeControl.Initialize(searchStrategy, int(en.Pos.Turn), en.Pos.FullMoveNumber, clock)
+	if ponder {
+		en.Search.TimeControl.Ponder()
+	}
 
 	// Set default depth if not passed
 	if depthIndex != -1 {
 		depth, _ = strconv.Atoi(params[depthIndex+1])
 	}
 
-	go func() {
-		en.Search.IterativeDeepening(&en.Pos, depth, stdout)
-	}()
+	if err := en.Controller.Start(&en.Pos, depth, stdout); err != nil {
+		stdout <- "info string Error: " + err.Error()
+	}
+}
+
+// goParams are the keywords of the "go" command
+var goParams = []string{"searchmoves", "ponder", "wtime", "btime", "winc", "binc", "movestogo", "depth", "nodes", "mate", "movetime", "infinite"}
+
+// searchMoves returns the moves passed after the "searchmoves" param of the "go" command
+func searchMoves(params []string) (moves []string) {
+	index := findParam(params, "searchmoves")
+	if index == -1 {
+		return
+	}
+
+	for _, p := range params[index+1:] {
+		if slices.Contains(goParams, p) {
+			break
+		}
+		moves = append(moves, p)
+	}
+	return
 }
 
 // UciSetOptionCommandStruct represents the "setoption" command.
@@ -134,22 +189,52 @@ func (c *UciSetOptionCommandStruct) Execute(en *engine.Engine, stdout chan strin
 		optionValue = strings.Join(params[3:], " ")
 	}
 
-	switch optionName {
-	case "bookpath":
-		c.setBookPath(en, stdout, optionValue)
-	case "usebook":
-		c.setUseBook(en, stdout, optionValue)
-	case "uci_chess960":
-		c.setChess960(en, stdout, optionValue)
-	case "hash":
-		c.setHashSize(en, stdout, optionValue)
-	case "clearhash":
-		en.Search.TranspositionTable.Clear()
-		en.Search.Evaluation.Clear()
-	case "overhead":
-		c.setOverhead(en, stdout, optionValue)
-	default:
-		stdout <- "info string Error: Unknown option name: " + params[1]
+	// Options change the search state, so they are not applied until the current search is finished
+	setOption := func() {
+		switch optionName {
+		case "bookpath":
+			c.setBookPath(en, stdout, optionValue)
+		case "usebook":
+			c.setUseBook(en, stdout, optionValue)
+		case "ponder":
+			c.setPonder(en, stdout, optionValue)
+		case "uci_chess960":
+			c.setChess960(en, stdout, optionValue)
+		case "hash":
+			c.setHashSize(en, stdout, optionValue)
+		case "clearhash":
+			en.Search.ClearHash()
+		case "threads":
+			c.setThreads(en, stdout, optionValue)
+		case "multipv":
+			c.setMultiPV(en, stdout, optionValue)
+		case "uci_limitstrength":
+			c.setLimitStrength(en, stdout, optionValue)
+		case "uci_elo":
+			c.setElo(en, stdout, optionValue)
+		case "uci_showwdl":
+			c.setShowWDL(en, stdout, optionValue)
+		case "seed":
+			c.setSeed(en, stdout, optionValue)
+		case "overhead":
+			c.setOverhead(en, stdout, optionValue)
+		case "syzygypath":
+			c.setSyzygyPath(en, stdout, optionValue)
+		case "syzygyprobedepth":
+			c.setSyzygyProbeDepth(en, stdout, optionValue)
+		case "evalfile":
+			c.setEvalFile(en, stdout, optionValue)
+		case "usennue":
+			c.setUseNNUE(en, stdout, optionValue)
+		case "quiescentchecks":
+			c.setQuiescentChecks(en, stdout, optionValue)
+		default:
+			stdout <- "info string Error: Unknown option name: " + params[1]
+		}
+	}
+
+	if en.Controller.Run(setOption) {
+		stdout <- "info string Search running, option " + params[1] + " will be set when the search finishes"
 	}
 }
 
@@ -177,6 +262,12 @@ func (c *UciSetOptionCommandStruct) setChess960(en *engine.Engine, stdout chan s
 	stdout <- "option name UCI_Chess960 value " + strconv.FormatBool(en.Options.Chess960)
 }
 
+// setPonder handles the "setoption name Ponder" command logic
+func (c *UciSetOptionCommandStruct) setPonder(en *engine.Engine, stdout chan string, value string) {
+	en.Options.Ponder = value == "true"
+	stdout <- "option name Ponder value " + strconv.FormatBool(en.Options.Ponder)
+}
+
 // setHashSize handles the "setoption name Hash" command logic
 func (c *UciSetOptionCommandStruct) setHashSize(en *engine.Engine, stdout chan string, value string) {
 	sizeInMb, err := strconv.Atoi(value)
@@ -188,6 +279,62 @@ func (c *UciSetOptionCommandStruct) setHashSize(en *engine.Engine, stdout chan s
 	stdout <- "option name Hash value " + value
 }
 
+// setThreads handles the "setoption name Threads" command logic
+func (c *UciSetOptionCommandStruct) setThreads(en *engine.Engine, stdout chan string, value string) {
+	threads, err := strconv.Atoi(value)
+	if err != nil || (threads < MinThreads || threads > MaxThreads) {
+		return
+	}
+
+	en.Search.SetThreads(threads)
+	stdout <- "option name Threads value " + value
+}
+
+// setMultiPV handles the "setoption name MultiPV" command logic
+func (c *UciSetOptionCommandStruct) setMultiPV(en *engine.Engine, stdout chan string, value string) {
+	multiPV, err := strconv.Atoi(value)
+	if err != nil || (multiPV < 1 || multiPV > engine.MaxLegalMoves) {
+		return
+	}
+
+	en.Search.MultiPV = multiPV
+	stdout <- "option name MultiPV value " + value
+}
+
+// setLimitStrength handles the "setoption name UCI_LimitStrength" command logic
+func (c *UciSetOptionCommandStruct) setLimitStrength(en *engine.Engine, stdout chan string, value string) {
+	en.Search.Strength.Limited = value == "true"
+	stdout <- "option name UCI_LimitStrength value " + strconv.FormatBool(en.Search.Strength.Limited)
+}
+
+// setElo handles the "setoption name UCI_Elo" command logic
+func (c *UciSetOptionCommandStruct) setElo(en *engine.Engine, stdout chan string, value string) {
+	elo, err := strconv.Atoi(value)
+	if err != nil || (elo < engine.MinElo || elo > engine.MaxElo) {
+		return
+	}
+
+	en.Search.Strength.SetElo(elo)
+	stdout <- "option name UCI_Elo value " + value
+}
+
+// setShowWDL handles the "setoption name UCI_ShowWDL" command logic
+func (c *UciSetOptionCommandStruct) setShowWDL(en *engine.Engine, stdout chan string, value string) {
+	en.Search.ShowWDL = value == "true"
+	stdout <- "option name UCI_ShowWDL value " + strconv.FormatBool(en.Search.ShowWDL)
+}
+
+// setSeed handles the "setoption name Seed" command logic
+func (c *UciSetOptionCommandStruct) setSeed(en *engine.Engine, stdout chan string, value string) {
+	seed, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return
+	}
+
+	en.Search.Strength.Seed = seed
+	stdout <- "option name Seed value " + value
+}
+
 // setOverhead handles the "setoption name Overhead" command logic
 func (c *UciSetOptionCommandStruct) setOverhead(en *engine.Engine, stdout chan string, value string) {
 	overheadMs, err := strconv.Atoi(value)
@@ -199,12 +346,79 @@ func (c *UciSetOptionCommandStruct) setOverhead(en *engine.Engine, stdout chan s
 	stdout <- "option name Overhead value " + value
 }
 
+// setSyzygyPath handles the "setoption name SyzygyPath" command logic
+func (c *UciSetOptionCommandStruct) setSyzygyPath(en *engine.Engine, stdout chan string, value string) {
+	tables, err := en.Search.SetSyzygyPath(value)
+	if err != nil {
+		stdout <- "option name SyzygyPath value " + err.Error()
+		return
+	}
+
+	stdout <- "option name SyzygyPath set tables found " + strconv.Itoa(tables)
+}
+
+// setSyzygyProbeDepth handles the "setoption name SyzygyProbeDepth" command logic
+func (c *UciSetOptionCommandStruct) setSyzygyProbeDepth(en *engine.Engine, stdout chan string, value string) {
+	depth, err := strconv.Atoi(value)
+	if err != nil || (depth < 1 || depth > engine.MaxSearchDepth) {
+		return
+	}
+
+	en.Search.SyzygyProbeDepth = depth
+	stdout <- "option name SyzygyProbeDepth value " + value
+}
+
+// setEvalFile handles the "setoption name EvalFile" command logic
+func (c *UciSetOptionCommandStruct) setEvalFile(en *engine.Engine, stdout chan string, value string) {
+	if err := en.Search.SetEvalFile(value); err != nil {
+		stdout <- "option name EvalFile value " + err.Error()
+		return
+	}
+
+	stdout <- "option name EvalFile value " + value
+}
+
+// setUseNNUE handles the "setoption name UseNNUE" command logic
+func (c *UciSetOptionCommandStruct) setUseNNUE(en *engine.Engine, stdout chan string, value string) {
+	en.Search.SetUseNNUE(value == "true")
+	stdout <- "option name UseNNUE value " + strconv.FormatBool(value == "true")
+}
+
+// setQuiescentChecks handles the "setoption name QuiescentChecks" command logic
+func (c *UciSetOptionCommandStruct) setQuiescentChecks(en *engine.Engine, stdout chan string, value string) {
+	en.Search.QuiescentChecks = value == "true"
+	stdout <- "option name QuiescentChecks value " + strconv.FormatBool(en.Search.QuiescentChecks)
+}
+
 // UciStopCommandStruct represents the "stop" command.
 type UciStopCommandStruct struct{}
 
 // Execute handles the "stop" command logic.
 func (c *UciStopCommandStruct) Execute(en *engine.Engine, stdout chan string, params ...string) {
-	en.Search.Stop()
+	en.Controller.Stop()
+}
+
+// UciDebugCommandStruct represents the "debug" command.
+type UciDebugCommandStruct struct{}
+
+// Execute handles the "debug" command logic.
+func (c *UciDebugCommandStruct) Execute(en *engine.Engine, stdout chan string, params ...string) {
+	if len(params) < 1 || (params[0] != "on" && params[0] != "off") {
+		stdout <- "info string Error: Invalid debug format"
+		return
+	}
+
+	en.Controller.Run(func() {
+		en.Search.Debug = params[0] == "on"
+	})
+}
+
+// UciPonderHitCommandStruct represents the "ponderhit" command.
+type UciPonderHitCommandStruct struct{}
+
+// Execute handles the "ponderhit" command logic.
+func (c *UciPonderHitCommandStruct) Execute(en *engine.Engine, stdout chan string, params ...string) {
+	en.Controller.PonderHit()
 }
 
 // findParam returns the index if the passed params slice contains the searched param string or -1 if not
@@ -252,12 +466,53 @@ func (c *DivideCommandStruct) Execute(en *engine.Engine, stdout chan string, par
 	}
 }
 
+// EvalCommandStruct prints the terms of the hand crafted evaluation of the current position
+type EvalCommandStruct struct{}
+
+func (c *EvalCommandStruct) Execute(en *engine.Engine, stdout chan string, params ...string) {
+	en.Controller.Run(func() {
+		pos := en.Pos
+		trace := engine.Trace(&pos)
+		stdout <- trace.String()
+
+		// The search evaluation can differ from the hand crafted one by the endgame tables or the network
+		if score := en.Search.Evaluation.Evaluate(&pos); score != trace.Score {
+			stdout <- "Search evaluation: " + strconv.FormatFloat(float64(score)/100, 'f', 2, 64) + " (side to move)"
+		}
+	})
+}
+
 // TTStatsCommandStruct returns usage stats for the transposition table and pawn hash table
 type TTStatsCommandStruct struct{}
 
 func (c *TTStatsCommandStruct) Execute(en *engine.Engine, stdout chan string, params ...string) {
-	stdout <- "Transposition Table:"
-	stdout <- en.Search.TranspositionTable.Stats()
-	stdout <- "Evaluation Pawn Hash Table:"
-	stdout <- en.Search.Evaluation.PawnCache.Stats()
+	en.Controller.Run(func() {
+		stdout <- "Transposition Table:"
+		stdout <- en.Search.TranspositionTable.Stats(en.Search.TTStats())
+		stdout <- "Evaluation Pawn Hash Table:"
+		stdout <- en.Search.Evaluation.PawnCache.Stats()
+	})
+}
+
+// BenchCommandStruct searches a fixed set of positions and returns the total nodes searched and the speed of the search
+type BenchCommandStruct struct{}
+
+func (c *BenchCommandStruct) Execute(en *engine.Engine, stdout chan string, params ...string) {
+	depth := engine.BenchDepth
+	if len(params) > 0 {
+		d, err := strconv.Atoi(params[0])
+		if err != nil || d < 1 {
+			stdout <- "invalid command"
+			return
+		}
+		depth = d
+	}
+
+	en.Controller.Run(func() {
+		start := time.Now()
+		nodes := engine.Bench(depth)
+		elapsed := max(time.Since(start).Milliseconds(), 1)
+		stdout <- "nodes " + strconv.FormatUint(nodes, 10) + " time " + strconv.FormatInt(elapsed, 10) +
+			" nps " + strconv.FormatUint(nodes*1000/uint64(elapsed), 10)
+	})
 }
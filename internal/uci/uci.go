@@ -30,15 +30,22 @@ func (uci *UciProtocol) Execute(command string, stdout chan string, params ...st
 		"position":   &UciPositionCommandStruct{},
 		"go":         &UciGoCommandStruct{},
 		"stop":       &UciStopCommandStruct{},
+		"ponderhit":  &UciPonderHitCommandStruct{},
 		"setoption":  &UciSetOptionCommandStruct{},
+		"debug":      &UciDebugCommandStruct{},
 
 		// utility/debug commands
 		"d":       &PrintBoardCommandStruct{},
+		"eval":    &EvalCommandStruct{},
 		"ttstats": &TTStatsCommandStruct{},
 		"perft":   &PerftCommandStruct{},
 		"divide":  &DivideCommandStruct{},
+		"bench":   &BenchCommandStruct{},
 	}
 
+	// Apply the commands queued while the engine was thinking
+	uci.engine.Controller.Flush()
+
 	comm, exists := uciCommands[command]
 
 	if !exists {
@@ -62,6 +69,7 @@ func (uci *UciProtocol) Start() {
 	for {
 		command := <-stdin
 		if command == "quit" {
+			uci.engine.Controller.Stop()
 			break
 		}
 
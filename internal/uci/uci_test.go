@@ -0,0 +1,169 @@
+package uci
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gabtar/aconcagua/internal/engine"
+)
+
+func TestCommandsWhileThinking(t *testing.T) {
+	uci := NewUciProtocol(engine.NewEngine())
+	stdout := make(chan string, 100000)
+
+	commands := []string{
+		"setoption name Threads value 2",
+		"position startpos moves e2e4",
+		"go infinite",
+		"position startpos moves d2d4 d7d5",
+		"setoption name Hash value 2",
+		"setoption name ClearHash",
+		"ucinewgame",
+		"go depth 3",
+		"ttstats",
+		"stop",
+		"go wtime 100 btime 100 winc 0 binc 0",
+		"setoption name Hash value 4",
+		"isready",
+		"stop",
+		"go ponder wtime 1000 btime 1000 winc 0 binc 0",
+		"ponderhit",
+		"stop",
+	}
+
+	for range 10 {
+		for _, command := range commands {
+			parts := strings.Split(command, " ")
+			uci.Execute(parts[0], stdout, parts[1:]...)
+		}
+	}
+	uci.engine.Controller.Stop()
+	close(stdout)
+
+	bestMoves := 0
+	for line := range stdout {
+		if strings.HasPrefix(line, "bestmove") {
+			bestMoves++
+		}
+	}
+
+	expected := 30
+	if bestMoves != expected {
+		t.Errorf("Expected: %v, got: %v", expected, bestMoves)
+	}
+}
+
+func TestGoRightAfterBestMove(t *testing.T) {
+	uci := NewUciProtocol(engine.NewEngine())
+	stdout := make(chan string, 100000)
+
+	for range 50 {
+		uci.Execute("go", stdout, "depth", "2")
+		for line := range stdout {
+			if strings.HasPrefix(line, "info string Error") {
+				t.Fatalf("Expected search to start, got: %v", line)
+			}
+			if strings.HasPrefix(line, "bestmove") {
+				break
+			}
+		}
+	}
+}
+
+func TestPonderHitWhileSearching(t *testing.T) {
+	uci := NewUciProtocol(engine.NewEngine())
+	stdout := make(chan string, 100000)
+	uci.Execute("debug", stdout, "on") // Debug output reads the time limits while searching
+
+	for range 5 {
+		uci.Execute("go", stdout, "ponder", "wtime", "500", "btime", "500", "winc", "0", "binc", "0")
+		for line := range stdout {
+			if strings.HasPrefix(line, "info depth 3") {
+				break
+			}
+		}
+		uci.Execute("ponderhit", stdout)
+
+		timeout := time.After(5 * time.Second)
+	waitBestMove:
+		for {
+			select {
+			case line := <-stdout:
+				if strings.HasPrefix(line, "bestmove") {
+					break waitBestMove
+				}
+			case <-timeout:
+				t.Fatalf("Expected: %v, got: %v", "bestmove after the ponderhit", "no best move")
+			}
+		}
+	}
+}
+
+func TestBenchReportsNodes(t *testing.T) {
+	uci := NewUciProtocol(engine.NewEngine())
+	stdout := make(chan string, 10)
+
+	uci.Execute("bench", stdout, "3")
+
+	got := <-stdout
+	if !strings.HasPrefix(got, "nodes ") || !strings.Contains(got, " nps ") {
+		t.Errorf("Expected: %v, got: %v", "nodes <n> time <ms> nps <n>", got)
+	}
+}
+
+func TestSetSyzygyPath(t *testing.T) {
+	uci := NewUciProtocol(engine.NewEngine())
+	stdout := make(chan string, 10)
+
+	uci.Execute("setoption", stdout, "name", "SyzygyPath", "value", "../syzygy/testdata")
+
+	expected := "option name SyzygyPath set tables found 2"
+	got := <-stdout
+	if got != expected {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
+
+func TestSetUseNNUE(t *testing.T) {
+	en := engine.NewEngine()
+	uci := NewUciProtocol(en)
+	stdout := make(chan string, 10)
+
+	uci.Execute("setoption", stdout, "name", "UseNNUE", "value", "true")
+
+	expected := "option name UseNNUE value true"
+	got := <-stdout
+	if got != expected || en.Search.Evaluation.Network == nil {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
+
+func TestSetQuiescentChecks(t *testing.T) {
+	en := engine.NewEngine()
+	uci := NewUciProtocol(en)
+	stdout := make(chan string, 10)
+
+	uci.Execute("setoption", stdout, "name", "QuiescentChecks", "value", "true")
+
+	expected := "option name QuiescentChecks value true"
+	got := <-stdout
+	if got != expected || !en.Search.QuiescentChecks {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}
+
+func TestEvalCommand(t *testing.T) {
+	en := engine.NewEngine()
+	uci := NewUciProtocol(en)
+	stdout := make(chan string, 10)
+
+	uci.Execute("position", stdout, "startpos", "moves", "e2e4")
+	uci.Execute("eval", stdout)
+
+	expected := "Final evaluation"
+	got := <-stdout
+	if !strings.Contains(got, expected) {
+		t.Errorf("Expected: %v, got: %v", expected, got)
+	}
+}